@@ -0,0 +1,181 @@
+package pgx
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var aggRegexp = regexp.MustCompile(`^(count|sum|avg|min|max)\((\*|distinct [a-z0-9_]+|[a-z0-9_]+)\)$`)
+
+// aggregateColumn returns the c-th column of column name, or an error if it doesn't exist
+func (b *BaseModel) aggregateColumn(column string) (int, error) {
+	c := b.columnIndex(column)
+	if c == -1 {
+		return -1, errors.New("table " + b.TableName + " has no column " + column)
+	}
+	return c, nil
+}
+
+// aggregateFloat runs fn(column) over rows matching 'where', returns 0 if there's no row
+func (b *BaseModel) aggregateFloat(fn, column, where string, args []interface{}) (float64, error) {
+	_, e := b.aggregateColumn(column)
+	if e != nil {
+		return 0, e
+	}
+	var num float64
+	query := `select coalesce(` + fn + `(` + column + `),0) from ` + b.fromTable() + toWhere(where)
+	e = b.queryRow(query, args, &num)
+	if e != nil {
+		return 0, toError(e, query)
+	}
+	return num, nil
+}
+
+// aggregateField runs fn(column) over rows matching 'where', returns a value of the column's field type, or nil if there's no row
+func (b *BaseModel) aggregateField(fn, column, where string, args []interface{}) (interface{}, error) {
+	c, e := b.aggregateColumn(column)
+	if e != nil {
+		return nil, e
+	}
+	v := reflect.New(reflect.PtrTo(b.Type.Field(b.fieldIndexes[c]).Type))
+	query := `select ` + fn + `(` + column + `) from ` + b.fromTable() + toWhere(where)
+	e = b.queryRow(query, args, v.Interface())
+	if e != nil {
+		return nil, toError(e, query)
+	}
+	if v.Elem().IsNil() {
+		return nil, nil
+	}
+	return v.Elem().Elem().Interface(), nil
+}
+
+// SumWhere returns sum of numeric column over rows matching 'where', as int64 for integer columns and float64 for others, 0 if there's no row
+func (b *BaseModel) SumWhere(column, where string, args ...interface{}) (interface{}, error) {
+	c, e := b.aggregateColumn(column)
+	if e != nil {
+		return nil, e
+	}
+	t := b.Type.Field(b.fieldIndexes[c]).Type
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8, reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+	default:
+		if t != reflect.TypeOf(sql.NullInt64{}) && t != reflect.TypeOf(sql.NullInt32{}) {
+			return b.aggregateFloat("sum", column, where, args)
+		}
+	}
+
+	var num int64
+	query := `select coalesce(sum(` + column + `),0) from ` + b.fromTable() + toWhere(where)
+	e = b.queryRow(query, args, &num)
+	if e != nil {
+		return nil, toError(e, query)
+	}
+	return num, nil
+}
+
+// AvgWhere returns average of numeric column over rows matching 'where', 0 if there's no row
+func (b *BaseModel) AvgWhere(column, where string, args ...interface{}) (float64, error) {
+	return b.aggregateFloat("avg", column, where, args)
+}
+
+// MinWhere returns minimum of column over rows matching 'where', typed as the column's field (e.g. int, time.Time), nil if there's no row
+func (b *BaseModel) MinWhere(column, where string, args ...interface{}) (interface{}, error) {
+	return b.aggregateField("min", column, where, args)
+}
+
+// MaxWhere returns maximum of column over rows matching 'where', typed as the column's field (e.g. int, time.Time), nil if there's no row
+func (b *BaseModel) MaxWhere(column, where string, args ...interface{}) (interface{}, error) {
+	return b.aggregateField("max", column, where, args)
+}
+
+// GetGroupBySQL returns select SQL grouping rows by result type t. Fields tagged `agg:"sum(amount)"` select the aggregate as their `db` tag,
+// other `db` tagged fields are group by columns
+func (b *BaseModel) GetGroupBySQL(t reflect.Type) (string, string, error) {
+	columns := []string{}
+	groups := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		dbTag, ok := field.Tag.Lookup("db")
+		if !ok || dbTag == "-" {
+			continue
+		}
+		agg, ok := field.Tag.Lookup("agg")
+		if !ok {
+			_, e := b.aggregateColumn(dbTag)
+			if e != nil {
+				return "", "", e
+			}
+			columns = append(columns, dbTag)
+			groups = append(groups, dbTag)
+			continue
+		}
+
+		agg = strings.ToLower(strings.TrimSpace(agg))
+		matches := aggRegexp.FindStringSubmatch(agg)
+		if matches == nil {
+			return "", "", errors.New("Invalid agg tag format:" + agg + " for field " + field.Name)
+		}
+		column := strings.TrimPrefix(matches[2], "distinct ")
+		if column != "*" {
+			_, e := b.aggregateColumn(column)
+			if e != nil {
+				return "", "", e
+			}
+		} else if matches[1] != "count" {
+			return "", "", errors.New("Invalid agg tag format:" + agg + " for field " + field.Name)
+		}
+		columns = append(columns, agg+` as `+dbTag)
+	}
+	if len(groups) == 0 {
+		return "", "", errors.New("result type " + t.String() + " has no group by field")
+	}
+
+	query := `select ` + strings.Join(columns, ",") + ` from ` + b.fromTable()
+	return query, ` group by ` + strings.Join(groups, ",") + ` order by ` + strings.Join(groups, ","), nil
+}
+
+// GroupBy groups rows matching 'where' by the plain `db` tagged fields of dst (*[]struct or *[]*struct),
+// scanning per-group aggregates into fields tagged like `db:"total" agg:"sum(amount)"`
+func (b *BaseModel) GroupBy(dst interface{}, where string, args ...interface{}) error {
+	dstValue := reflect.ValueOf(dst)
+	if dstValue.Kind() != reflect.Ptr || dstValue.Elem().Kind() != reflect.Slice {
+		return errors.New("dst must be pointer to slice:" + dstValue.Type().String())
+	}
+	sliceValue := dstValue.Elem()
+	t := sliceValue.Type().Elem()
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return errors.New("dst element must be struct type:" + t.String())
+	}
+
+	query, groupBy, e := b.GetGroupBySQL(t)
+	if e != nil {
+		return e
+	}
+	query = query + toWhere(where) + groupBy
+
+	rows, e := b.query(query, args...)
+	if e != nil {
+		return toError(e, query)
+	}
+	vs := reflect.MakeSlice(sliceValue.Type(), 0, 2)
+	e = scanRows(rows, t, func(v reflect.Value) bool {
+		if isPtr {
+			vs = reflect.Append(vs, v)
+		} else {
+			vs = reflect.Append(vs, v.Elem())
+		}
+		return true
+	})
+	if e != nil {
+		return e
+	}
+	sliceValue.Set(vs)
+	return nil
+}
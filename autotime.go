@@ -0,0 +1,77 @@
+package pgx
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/StevenZack/tools/strToolkit"
+)
+
+const autoTimeType = "timestamp with time zone not null default now()"
+
+// toSettable returns value itself if it can be set, or a settable copy of it
+func toSettable(value reflect.Value) reflect.Value {
+	if value.CanSet() {
+		return value
+	}
+	v := reflect.New(value.Type()).Elem()
+	v.Set(value)
+	return v
+}
+
+// isAutoCreateTime reports whether the i-th column is an autoCreateTime column, which is never updated
+func (b *BaseModel) isAutoCreateTime(i int) bool {
+	for _, c := range b.autoCreateTime {
+		if c == i {
+			return true
+		}
+	}
+	return false
+}
+
+// setAutoTime fills zero autoCreateTime and autoUpdateTime fields with now on insert, and autoUpdateTime fields on update
+func (b *BaseModel) setAutoTime(value reflect.Value, insert bool) {
+	now := reflect.ValueOf(time.Now())
+	if insert {
+		for _, i := range b.autoCreateTime {
+			if b.columnField(value, i).Interface().(time.Time).IsZero() {
+				b.columnField(value, i).Set(now)
+			}
+		}
+	}
+	for _, i := range b.autoUpdateTime {
+		if insert && !b.columnField(value, i).Interface().(time.Time).IsZero() {
+			continue
+		}
+		b.columnField(value, i).Set(now)
+	}
+}
+
+// toAutoTimeSets appends autoUpdateTime columns not assigned in sets
+func (b *BaseModel) toAutoTimeSets(sets string) string {
+	targets := toSetTargets(sets)
+	for _, i := range b.autoUpdateTime {
+		if targets[b.dbTags[i]] {
+			continue
+		}
+		sets += "," + b.dbTags[i] + "=now()"
+	}
+	return sets
+}
+
+// toSetTargets returns columns assigned in sets, like "a=$1,(b,c)=($2,$3)"
+func toSetTargets(sets string) map[string]bool {
+	targets := make(map[string]bool)
+	items, e := splitTopLevel(sets)
+	if e != nil {
+		return targets
+	}
+	for _, item := range items {
+		target := strings.Trim(strToolkit.SubBefore(item, "=", ""), " ()")
+		for _, column := range strings.Split(target, ",") {
+			targets[strings.Trim(column, ` "`)] = true
+		}
+	}
+	return targets
+}
@@ -0,0 +1,26 @@
+package pgx
+
+import "testing"
+
+func TestToAutoTimeSets(t *testing.T) {
+	b := &BaseModel{
+		dbTags:         []string{"id", "name", "last_updated_at", "updated_at"},
+		autoUpdateTime: []int{3},
+	}
+	tests := []struct {
+		sets string
+		want string
+	}{
+		{sets: "name=$1", want: "name=$1,updated_at=now()"},
+		{sets: "last_updated_at=$1", want: "last_updated_at=$1,updated_at=now()"},
+		{sets: "name=$1, updated_at = $2", want: "name=$1, updated_at = $2"},
+		{sets: `"updated_at"=$1`, want: `"updated_at"=$1`},
+		{sets: "(name,updated_at)=($1,$2)", want: "(name,updated_at)=($1,$2)"},
+		{sets: "name=coalesce($1,'updated_at=')", want: "name=coalesce($1,'updated_at='),updated_at=now()"},
+	}
+	for _, test := range tests {
+		if got := b.toAutoTimeSets(test.sets); got != test.want {
+			t.Errorf("toAutoTimeSets(%q) = %q, want %q", test.sets, got, test.want)
+		}
+	}
+}
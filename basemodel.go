@@ -1,13 +1,14 @@
 package pgx
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/StevenZack/tools/strToolkit"
 	"github.com/iancoleman/strcase"
@@ -16,28 +17,48 @@ import (
 )
 
 type BaseModel struct {
-	Type      reflect.Type
-	Dsn       string
-	Pool      *sql.DB
-	Database  string
-	Schema    string
-	TableName string
-
-	dbTags  []string
-	pgTypes []string
+	Type       reflect.Type
+	Dsn        string
+	Pool       *sql.DB
+	Database   string
+	Schema     string
+	TableName  string
+	Logger     Logger
+	QueryHooks []QueryHook
+
+	dbTags       []string
+	pgTypes      []string
+	fieldIndexes []int
+	fks          []foreignKey
+	pks          []int
+
+	constraints []Constraint
+	softDelete  string
+	withDeleted bool
+
+	autoCreateTime []int
+	autoUpdateTime []int
+	version        int
+	validators     []*fieldValidator
+	readonly       []int
+
+	relations []relation
+	preloads  []string
+	selects   []string
+	omits     []string
+	ctx       context.Context
 }
 
-func NewBaseModel(dsn string, data interface{}) (*BaseModel, error) {
-	model, _, e := NewBaseModelWithCreated(dsn, data)
+func NewBaseModel(dsn string, data interface{}, opts ...Option) (*BaseModel, error) {
+	model, _, e := NewBaseModelWithCreated(dsn, data, opts...)
 	return model, e
 }
 
-func NewBaseModelWithCreated(dsn string, data interface{}) (*BaseModel, bool, error) {
+func NewBaseModelWithCreated(dsn string, data interface{}, opts ...Option) (*BaseModel, bool, error) {
 	created := false
 	t := reflect.TypeOf(data)
 	dsnMap, e := ParseDsn(dsn)
 	if e != nil {
-		log.Println(e)
 		return nil, false, e
 	}
 
@@ -47,6 +68,8 @@ func NewBaseModelWithCreated(dsn string, data interface{}) (*BaseModel, bool, er
 		Database:  dsnMap["dbname"],
 		Schema:    "public",
 		TableName: ToTableName(t.Name()),
+		version:   -1,
+		Logger:    DefaultLogger,
 	}
 
 	//validate
@@ -57,19 +80,40 @@ func NewBaseModelWithCreated(dsn string, data interface{}) (*BaseModel, bool, er
 	//pool
 	model.Pool, e = sql.Open("postgres", dsn)
 	if e != nil {
-		log.Println(e)
 		return nil, false, e
 	}
+	for _, opt := range opts {
+		opt(model)
+	}
 
 	//check data
 	if t.Kind() == reflect.Ptr {
 		return nil, false, errors.New("data must be struct type")
 	}
 
+	//primary key
+	customPk := false
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("pk"); ok {
+			customPk = true
+		}
+	}
+
 	indexes := make(map[string]string)
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		if i == 0 {
+		c := len(model.dbTags)
+
+		//relation
+		if relTag, ok := field.Tag.Lookup("rel"); ok {
+			rel, e := toRelation(t, i, field, relTag)
+			if e != nil {
+				return nil, false, e
+			}
+			model.relations = append(model.relations, rel)
+			continue
+		}
+		if c == 0 && !customPk {
 			switch field.Type.Kind() {
 			case reflect.Uint,
 				reflect.Uint64,
@@ -86,48 +130,119 @@ func NewBaseModelWithCreated(dsn string, data interface{}) (*BaseModel, bool, er
 		if !ok {
 			return nil, false, errors.New("field " + field.Name + " has no `db` tag specified")
 		}
-		if i == 0 && dbTag != "id" {
+		if c == 0 && !customPk && dbTag != "id" {
 			return nil, false, errors.New("The first field's `db` tag must be id")
 		}
+		if _, ok := field.Tag.Lookup("pk"); ok || (c == 0 && !customPk) {
+			model.pks = append(model.pks, c)
+		}
 		if dbTag != strcase.ToSnake(dbTag) {
 			return nil, false, errors.New("Field '" + field.Name + "'s `db` tag is not in snake case")
 		}
 
+		//soft delete
+		if _, ok := field.Tag.Lookup("softdelete"); ok || dbTag == "deleted_at" {
+			if field.Type == reflect.TypeOf(sql.NullTime{}) {
+				model.softDelete = dbTag
+			} else if ok {
+				return nil, false, errors.New("Field '" + field.Name + "' with `softdelete` tag must be sql.NullTime type")
+			}
+		}
+
+		//version
+		if _, ok := field.Tag.Lookup("version"); ok {
+			switch field.Type.Kind() {
+			case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16:
+			default:
+				return nil, false, errors.New("Field '" + field.Name + "' with `version` tag must be integer type")
+			}
+			if model.version > -1 {
+				return nil, false, errors.New("Duplicated `version` tag on field '" + field.Name + "'")
+			}
+			model.version = c
+		}
+
 		//index
 		if index, ok := field.Tag.Lookup("index"); ok {
 			indexes[dbTag] = index
 		}
 
+		//fk
+		if fkTag, ok := field.Tag.Lookup("fk"); ok {
+			fk, e := toForeignKey(dbTag, fkTag)
+			if e != nil {
+				return nil, false, e
+			}
+			model.fks = append(model.fks, fk)
+		}
+
 		//limit
 		limit := 0
 		if limitStr, ok := field.Tag.Lookup("limit"); ok {
 			limit, e = strconv.Atoi(limitStr)
 			if e != nil {
-				log.Println(e)
 				return nil, false, errors.New("Invalid limit tag format:" + limitStr + " for field " + field.Name)
 			}
 		}
 
+		//validator
+		validator, e := toFieldValidator(i, field, dbTag, limit)
+		if e != nil {
+			return nil, false, e
+		}
+		if validator != nil {
+			model.validators = append(model.validators, validator)
+		}
+
 		//pgType
 		pgType, e := ToPostgreType(field.Type, dbTag, limit)
 		if e != nil {
-			log.Println(e)
 			return nil, false, fmt.Errorf("Field %s:%w", field.Name, e)
 		}
 
+		//auto time
+		_, autoCreate := field.Tag.Lookup("autoCreateTime")
+		_, autoUpdate := field.Tag.Lookup("autoUpdateTime")
+		isTime := field.Type == reflect.TypeOf(time.Time{})
+		if (autoCreate || autoUpdate) && !isTime {
+			return nil, false, errors.New("Field '" + field.Name + "' with `autoCreateTime` or `autoUpdateTime` tag must be time.Time type")
+		}
+		if autoCreate || (isTime && dbTag == "created_at") {
+			model.autoCreateTime = append(model.autoCreateTime, c)
+			pgType = autoTimeType
+		}
+		if autoUpdate || (isTime && dbTag == "updated_at") {
+			model.autoUpdateTime = append(model.autoUpdateTime, c)
+			pgType = autoTimeType
+		}
+
+		//generated, readonly
+		if expr, ok := field.Tag.Lookup("generated"); ok {
+			if model.isPk(c) || expr == "" {
+				return nil, false, errors.New("Invalid generated tag format:" + expr + " for field " + field.Name)
+			}
+			pgType = toGeneratedType(pgType, expr)
+			model.readonly = append(model.readonly, c)
+		} else if _, ok := field.Tag.Lookup("readonly"); ok {
+			model.readonly = append(model.readonly, c)
+		}
+
 		model.dbTags = append(model.dbTags, dbTag)
 		model.pgTypes = append(model.pgTypes, pgType)
+		model.fieldIndexes = append(model.fieldIndexes, i)
 	}
 	localIndexList, e := toIndexModels(indexes)
 	if e != nil {
-		log.Println(e)
+		return nil, false, e
+	}
+	model.constraints, e = toConstraints(t)
+	if e != nil {
 		return nil, false, e
 	}
 
 	//desc
-	remoteColumnList, e := DescTable(model.Pool, model.Database, model.Schema, model.TableName)
+	remoteColumnList, e := descTable(model.query, model.Database, model.Schema, model.TableName)
 	if e != nil {
-		log.Println(e)
 		return nil, false, e
 	}
 
@@ -135,13 +250,21 @@ func NewBaseModelWithCreated(dsn string, data interface{}) (*BaseModel, bool, er
 	if len(remoteColumnList) == 0 {
 		e = model.createTable()
 		if e != nil {
-			log.Println(e)
 			return nil, false, e
 		}
 		//create index
 		e = model.createIndexFromField(localIndexList)
 		if e != nil {
-			log.Println(e)
+			return nil, false, e
+		}
+		//create join tables
+		e = model.createJoinTables()
+		if e != nil {
+			return nil, false, e
+		}
+		registerModel(model)
+		e = model.createPendingJoinTables()
+		if e != nil {
 			return nil, false, e
 		}
 		return model, true, nil
@@ -161,10 +284,9 @@ func NewBaseModelWithCreated(dsn string, data interface{}) (*BaseModel, bool, er
 		remote, ok := remoteColumns[db]
 		if !ok {
 			//auto-create field on remote database
-			log.Println("Remote column '" + db + "' to be created")
+			model.log(LevelInfo, "remote column to be created", Field{Key: "column", Value: db})
 			e = model.addColumn(db, model.pgTypes[i])
 			if e != nil {
-				log.Println(e)
 				return nil, false, e
 			}
 			continue
@@ -179,6 +301,22 @@ func NewBaseModelWithCreated(dsn string, data interface{}) (*BaseModel, bool, er
 		if dbType != remoteType {
 			return nil, false, errors.New("Found local field " + db + "'s type '" + dbType + "' doesn't match remote column type:" + remoteType)
 		}
+
+		//generated check
+		remoteGenerated := remote.IsGenerated == "ALWAYS"
+		if model.isGenerated(i) && !remoteGenerated {
+			return nil, false, errors.New("Found local field " + db + " is generated but remote column is not, drop the column to recreate it")
+		}
+		if model.isGenerated(i) && !model.generatedExprEqual(remote.GenerationExpression, i) {
+			return nil, false, errors.New("Found local field " + db + "'s generated expression '" + model.generatedExpr(i) + "' doesn't match remote column expression:" + remote.GenerationExpression + ", drop the column to recreate it")
+		}
+		if !model.isGenerated(i) && remoteGenerated {
+			model.log(LevelInfo, "remote column expression to be dropped", Field{Key: "column", Value: db})
+			e = model.dropColumnExpression(db)
+			if e != nil {
+				return nil, false, e
+			}
+		}
 	}
 
 	//remote columns to be dropped
@@ -186,20 +324,67 @@ func NewBaseModelWithCreated(dsn string, data interface{}) (*BaseModel, bool, er
 		_, ok := localColumns[remote.ColumnName]
 		if !ok {
 			//auto-drop remote column
-			log.Println("Remote column '" + remote.ColumnName + "' to be dropped")
+			model.log(LevelInfo, "remote column to be dropped", Field{Key: "column", Value: remote.ColumnName})
 			e = model.dropColumn(remote.ColumnName)
 			if e != nil {
-				log.Println(e)
 				return nil, false, e
 			}
 			continue
 		}
 	}
 
+	// constraint check
+	remoteConstraintList, e := model.GetConstraints()
+	if e != nil {
+		return nil, false, e
+	}
+	remoteConstraints := make(map[string]ConstraintSchema)
+	for _, remote := range remoteConstraintList {
+		remoteConstraints[remote.ConstraintName] = remote
+	}
+
+	// constraints to be created
+	localConstraints := make(map[string]Constraint)
+	for _, local := range model.constraints {
+		localConstraints[local.Name] = local
+		if remote, ok := remoteConstraints[local.Name]; ok {
+			if model.constraintDefEqual(remote.ConstraintDef, local) {
+				continue
+			}
+			//recreate constraint whose definition changed
+			model.log(LevelInfo, "remote constraint to be recreated", Field{Key: "constraint", Value: local.Name})
+			e = model.dropConstraint(local.Name)
+			if e != nil {
+				return nil, false, e
+			}
+			e = model.addConstraint(local)
+			if e != nil {
+				return nil, false, e
+			}
+			continue
+		}
+		model.log(LevelInfo, "remote constraint to be created", Field{Key: "constraint", Value: local.Name})
+		e = model.addConstraint(local)
+		if e != nil {
+			return nil, false, e
+		}
+	}
+
+	// constraints to be dropped
+	for _, remote := range remoteConstraintList {
+		if _, ok := localConstraints[remote.ConstraintName]; ok || model.isImplicitConstraint(remote) {
+			continue
+		}
+		model.log(LevelInfo, "remote constraint to be dropped", Field{Key: "constraint", Value: remote.ConstraintName})
+		e = model.dropConstraint(remote.ConstraintName)
+		if e != nil {
+			return nil, false, e
+		}
+	}
+
 	// index check
 	remoteIndexList, e := model.GetIndexes()
 	if e != nil {
-		log.Println(e)
 		return nil, false, e
 	}
 	remoteIndexes := make(map[string]IndexSchema)
@@ -209,49 +394,132 @@ func NewBaseModelWithCreated(dsn string, data interface{}) (*BaseModel, bool, er
 
 	// indexes to be created
 	localIndexes := make(map[string]indexModel)
+	claimedIndexes := make(map[string]bool)
+	for _, local := range localIndexList {
+		claimedIndexes[local.ToIndexName(model.TableName)] = true
+	}
+	for name := range localConstraints {
+		claimedIndexes[name] = true
+	}
 	for _, local := range localIndexList {
 		localIndexes[local.ToIndexName(model.TableName)] = local
 		remote, ok := remoteIndexes[local.ToIndexName(model.TableName)]
 		if !ok {
+			//same index under an old name
+			if renamed, ok := model.findRenamedIndex(local, remoteIndexList, claimedIndexes); ok {
+				model.log(LevelInfo, "remote index to be renamed", Field{Key: "index", Value: renamed.IndexName}, Field{Key: "name", Value: local.ToIndexName(model.TableName)})
+				e = model.renameIndex(renamed.IndexName, local.ToIndexName(model.TableName))
+				if e != nil {
+					return nil, false, e
+				}
+				claimedIndexes[renamed.IndexName] = true
+				localIndexes[renamed.IndexName] = local
+				continue
+			}
+
 			//auto-create index on remote database
-			log.Println("Remote index '" + local.ToIndexName(model.TableName) + "' to be created")
+			model.log(LevelInfo, "remote index to be created", Field{Key: "index", Value: local.ToIndexName(model.TableName)})
 			e = model.createIndex(local)
 			if e != nil {
-				log.Println(e)
 				return nil, false, e
 			}
 			continue
 		}
 
-		//unique check
-		if local.unique != strings.Contains(remote.IndexDef, "UNIQUE") {
-			return nil, false, errors.New("Index '" + local.ToIndexName(model.TableName) + "' unique option is inconsistant with remote database: " + strconv.FormatBool(local.unique) + " vs " + strconv.FormatBool(strings.Contains(remote.IndexDef, "UNIQUE")))
+		//definition check
+		if !model.indexDefEqual(remote.IndexDef, local) {
+			model.log(LevelInfo, "remote index to be recreated", Field{Key: "index", Value: local.ToIndexName(model.TableName)}, Field{Key: "remote", Value: remote.IndexDef})
+			e = model.recreateIndex(local)
+			if e != nil {
+				return nil, false, e
+			}
 		}
 	}
 
 	//indexes to be dropped
 	for _, remote := range remoteIndexList {
-		if strings.Contains(remote.IndexName, "_pkey") {
+		if remote.IsPrimary {
+			continue
+		}
+		//owned by unique or exclusion constraint
+		if _, ok := localConstraints[remote.IndexName]; ok {
 			continue
 		}
 		_, ok := localIndexes[remote.IndexName]
 		if !ok {
-			log.Println("Remote index '" + remote.IndexName + "' to be dropped")
-			e = model.dropIndex(remote.IndexName)
+			model.log(LevelInfo, "remote index to be dropped", Field{Key: "index", Value: remote.IndexName})
+			e = model.dropIndex(remote.IndexName, false)
 			if e != nil {
-				log.Println(e)
 				return nil, false, e
 			}
 			continue
 		}
 	}
 
+	// foreign key check
+	remoteFkList, e := model.GetForeignKeys()
+	if e != nil {
+		return nil, false, e
+	}
+	remoteFks := make(map[string]ForeignKeySchema)
+	for _, remote := range remoteFkList {
+		remoteFks[remote.ConstraintName] = remote
+	}
+
+	// foreign keys to be created
+	localFks := make(map[string]foreignKey)
+	for _, local := range model.fks {
+		name := local.ToConstraintName(model.TableName)
+		localFks[name] = local
+		remote, ok := remoteFks[name]
+		if ok && local.equal(remote, model.Schema) {
+			continue
+		}
+		if ok {
+			//references or rules changed, recreate
+			model.log(LevelInfo, "remote foreign key to be recreated", Field{Key: "constraint", Value: name})
+			e = model.dropConstraint(name)
+			if e != nil {
+				return nil, false, e
+			}
+		} else {
+			model.log(LevelInfo, "remote foreign key to be created", Field{Key: "constraint", Value: name})
+		}
+		e = model.addForeignKey(local)
+		if e != nil {
+			return nil, false, e
+		}
+	}
+
+	//foreign keys to be dropped
+	for _, remote := range remoteFkList {
+		_, ok := localFks[remote.ConstraintName]
+		if !ok {
+			model.log(LevelInfo, "remote foreign key to be dropped", Field{Key: "constraint", Value: remote.ConstraintName})
+			e = model.dropConstraint(remote.ConstraintName)
+			if e != nil {
+				return nil, false, e
+			}
+		}
+	}
+
+	//join tables
+	e = model.createJoinTables()
+	if e != nil {
+		return nil, false, e
+	}
+
+	registerModel(model)
+	e = model.createPendingJoinTables()
+	if e != nil {
+		return nil, false, e
+	}
 	return model, created, nil
 }
 
 func (b *BaseModel) createTable() error {
 	query := b.GetCreateTableSQL()
-	_, e := b.Pool.Exec(query)
+	_, e := b.exec(query)
 	if e != nil {
 		return fmt.Errorf("%w: %s", e, query)
 	}
@@ -259,18 +527,16 @@ func (b *BaseModel) createTable() error {
 }
 
 func (b *BaseModel) addColumn(name, typ string) error {
-	_, e := b.Pool.Exec(`alter table ` + b.Schema + `.` + b.TableName + ` add column ` + name + ` ` + typ)
+	_, e := b.exec(`alter table ` + b.Schema + `.` + b.TableName + ` add column ` + name + ` ` + typ)
 	if e != nil {
-		log.Println(e)
 		return e
 	}
 	return e
 }
 
 func (b *BaseModel) dropColumn(name string) error {
-	_, e := b.Pool.Exec(`alter table ` + b.Schema + `.` + b.TableName + ` drop column ` + name)
+	_, e := b.exec(`alter table ` + b.Schema + `.` + b.TableName + ` drop column ` + name)
 	if e != nil {
-		log.Println(e)
 		return e
 	}
 	return nil
@@ -282,78 +548,64 @@ func (b *BaseModel) GetCreateTableSQL() string {
 	for i, dbTag := range b.dbTags {
 		builder.WriteString(dbTag + " ")
 		builder.WriteString(b.pgTypes[i])
-		if i == 0 {
-			builder.WriteString(" primary key")
-		}
-		if i < len(b.dbTags)-1 {
-			builder.WriteString(",")
-		}
+		builder.WriteString(",")
+	}
+	builder.WriteString("primary key (" + strings.Join(b.GetPkTags(), ",") + ")")
+	for _, fk := range b.fks {
+		builder.WriteString("," + fk.ToConstraintDef(b.TableName))
+	}
+	for _, c := range b.constraints {
+		builder.WriteString("," + c.ToConstraintDef())
 	}
 	builder.WriteString(`)`)
 	return builder.String()
 }
 
-// GetInsertSQL returns insert SQL without returning id
+// GetInsertSQL returns insert SQL without returning id, serial and readonly columns are left to the database
 func (b *BaseModel) GetInsertSQL() ([]int, string) {
 	builder := new(strings.Builder)
 	builder.WriteString(`insert into ` + b.Schema + `.` + b.TableName + ` (`)
 
-	values := new(strings.Builder)
-	values.WriteString("values (")
-
 	argsIndex := []int{}
-
+	columns := []string{}
+	values := []string{}
 	for i, dbTag := range b.dbTags {
 		dbType := b.pgTypes[i]
-		if strings.Contains(dbType, "serial") {
+		if strings.Contains(dbType, "serial") || b.isReadonly(i) {
 			continue
 		}
 
-		argsIndex = append(argsIndex, i)
-
-		builder.WriteString(dbTag)
-		values.WriteString("$" + strconv.Itoa(len(argsIndex)))
-
-		if i < len(b.dbTags)-1 {
-			builder.WriteString(",")
-			values.WriteString(",")
-		}
-
+		argsIndex = append(argsIndex, b.fieldIndexes[i])
+		columns = append(columns, dbTag)
+		values = append(values, "$"+strconv.Itoa(len(argsIndex)))
 	}
 
+	builder.WriteString(strings.Join(columns, ","))
+	builder.WriteString(")values (")
+	builder.WriteString(strings.Join(values, ","))
 	builder.WriteString(")")
-	values.WriteString(")")
-
-	builder.WriteString(values.String())
 
 	return argsIndex, builder.String()
 }
 
-// GetInsertReturningSQL returns insert SQL with returning id
+// GetInsertReturningSQL returns insert SQL with returning primary keys
 func (b *BaseModel) GetInsertReturningSQL() ([]int, string) {
 	argsIndex, query := b.GetInsertSQL()
-	return argsIndex, query + " returning " + b.dbTags[0]
+	return argsIndex, query + " returning " + strings.Join(b.GetPkTags(), ",")
 }
 
-// GetSelectSQL returns fieldIndexes, and select SQL
+// GetSelectSQL returns fieldIndexes, and select SQL of columns narrowed by Select and Omit
 func (b *BaseModel) GetSelectSQL() ([]int, string) {
 	builder := new(strings.Builder)
 	builder.WriteString(`select `)
-	fieldIndexes := []int{}
-	for i, dbTag := range b.dbTags {
-		builder.WriteString(dbTag)
-		fieldIndexes = append(fieldIndexes, i)
-		if i < len(b.dbTags)-1 {
-			builder.WriteString(",")
-		}
-	}
-	builder.WriteString(" from " + b.TableName)
+	fieldIndexes, columns := b.selectColumns()
+	builder.WriteString(strings.Join(columns, ","))
+	builder.WriteString(" from " + b.fromTable())
 	return fieldIndexes, builder.String()
 }
 
-// Insert inserts v (*struct or struct type)
-func (b *BaseModel) Insert(v interface{}) (interface{}, error) {
-	//validate
+// toInsertValue checks the type of v (*struct or struct type), calls BeforeInsert, validates it and fills auto time fields
+func (b *BaseModel) toInsertValue(v interface{}) (reflect.Value, error) {
 	value := reflect.ValueOf(v)
 	t := value.Type()
 	if t.Kind() == reflect.Ptr {
@@ -361,32 +613,97 @@ func (b *BaseModel) Insert(v interface{}) (interface{}, error) {
 		value = value.Elem()
 	}
 	if t.String() != b.Type.String() {
-		return nil, errors.New("Wrong insert type:" + t.String() + " for table " + b.TableName)
+		return value, errors.New("Wrong insert type:" + t.String() + " for table " + b.TableName)
+	}
+	value = toSettable(value)
+	e := beforeInsert(value)
+	if e != nil {
+		return value, e
+	}
+	e = b.validate(value)
+	if e != nil {
+		return value, e
+	}
+	b.setAutoTime(value, true)
+	return value, nil
+}
+
+// Insert inserts v (*struct or struct type), returns the primary key, or []interface{} for composite primary keys
+func (b *BaseModel) Insert(v interface{}) (interface{}, error) {
+	//validate
+	value, e := b.toInsertValue(v)
+	if e != nil {
+		return nil, e
 	}
 
 	//args
 	argsIndex, query := b.GetInsertReturningSQL()
 	args := []interface{}{}
 	for _, i := range argsIndex {
-		field := value.Field(i)
-		if field.Kind() == reflect.Slice {
-			args = append(args, pq.Array(field.Interface()))
-			continue
-		}
-		args = append(args, field.Interface())
+		args = append(args, toArg(value.Field(i)))
 	}
 
 	//exec
-	id := reflect.New(b.Type.Field(0).Type)
-	e := b.Pool.QueryRow(query, args...).Scan(id.Interface())
+	ids := []interface{}{}
+	for _, pk := range b.pks {
+		ids = append(ids, reflect.New(b.Type.Field(b.fieldIndexes[pk]).Type).Interface())
+	}
+	e = b.queryRow(query, args, ids...)
+	if e != nil {
+		return nil, toError(e, query)
+	}
+	e = afterInsert(value)
 	if e != nil {
 		return nil, e
 	}
 
-	return id.Elem().Interface(), nil
+	for i, id := range ids {
+		ids[i] = reflect.ValueOf(id).Elem().Interface()
+	}
+	if len(ids) == 1 {
+		return ids[0], nil
+	}
+	return ids, nil
 }
 
-// InsertAll inserts vs ([]*struct or []struct type)
+// InsertReturning inserts v (*struct type), and writes back all columns of the inserted row into it, including database defaults
+func (b *BaseModel) InsertReturning(v interface{}) error {
+	//validate
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr {
+		return errors.New("Insert value must be pointer type:" + value.Type().String())
+	}
+	value, e := b.toInsertValue(v)
+	if e != nil {
+		return e
+	}
+	e = b.checkProjection()
+	if e != nil {
+		return e
+	}
+
+	//args
+	argsIndex, query := b.GetInsertSQL()
+	args := []interface{}{}
+	for _, i := range argsIndex {
+		args = append(args, toArg(value.Field(i)))
+	}
+	fieldIndexes, returning := b.GetReturningSQL()
+	query = query + returning
+
+	//exec
+	fieldArgs := []interface{}{}
+	for _, i := range fieldIndexes {
+		fieldArgs = append(fieldArgs, value.Field(i).Addr().Interface())
+	}
+	e = b.queryRow(query, args, fieldArgs...)
+	if e != nil {
+		return toError(e, query)
+	}
+	return afterInsert(value)
+}
+
+// InsertAll inserts vs ([]*struct or []struct type), and back-fills primary keys into its elements
 func (b *BaseModel) InsertAll(vs interface{}) error {
 	//validate
 	sliceValue := reflect.ValueOf(vs)
@@ -402,39 +719,88 @@ func (b *BaseModel) InsertAll(vs interface{}) error {
 		return errors.New("Wrong insert type:" + t.String() + " for table " + b.TableName)
 	}
 
+	//validate all before writing any
+	values := []reflect.Value{}
+	errs := ValidationErrors{}
+	for i := 0; i < sliceValue.Len(); i++ {
+		value := sliceValue.Index(i)
+		if value.Kind() == reflect.Ptr {
+			value = value.Elem()
+		}
+		value = toSettable(value)
+		e := beforeInsert(value)
+		if e != nil {
+			return e
+		}
+		e = b.validate(value)
+		if verrs, ok := e.(ValidationErrors); ok {
+			for _, v := range verrs {
+				v.Field = "[" + strconv.Itoa(i) + "]." + v.Field
+				errs = append(errs, v)
+			}
+		} else if e != nil {
+			return e
+		}
+		values = append(values, value)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
 	//prepare
-	argsIndex, query := b.GetInsertSQL()
+	argsIndex, query := b.GetInsertReturningSQL()
 
-	stmt, e := b.Pool.Prepare(query)
+	//not reported to hooks, since nothing is executed
+	stmt, e := b.Pool.PrepareContext(b.context(), query)
 	if e != nil {
-		return e
+		return toError(e, query)
 	}
 	defer stmt.Close()
 
 	//exec
-	for i := 0; i < sliceValue.Len(); i++ {
-		value := sliceValue.Index(i)
-		if value.Kind() == reflect.Ptr {
-			value = value.Elem()
-		}
+	for _, value := range values {
+		b.setAutoTime(value, true)
 
 		//args
 		args := []interface{}{}
 		for _, j := range argsIndex {
-			args = append(args, value.Field(j).Interface())
+			args = append(args, toArg(value.Field(j)))
+		}
+		ids := []interface{}{}
+		for _, pk := range b.pks {
+			ids = append(ids, b.columnField(value, pk).Addr().Interface())
 		}
 
-		_, e := stmt.Exec(args...)
+		e = b.run(query, args, func() (int64, error) {
+			e := stmt.QueryRowContext(b.context(), args...).Scan(ids...)
+			if e != nil {
+				return -1, e
+			}
+			return 1, nil
+		})
+		if e != nil {
+			return fmt.Errorf("insert failed when insert %v:%w", value.Interface(), toError(e, query))
+		}
+		e = afterInsert(value)
 		if e != nil {
-			return fmt.Errorf("insert failed when insert %v:%w", value.Interface(), e)
+			return e
 		}
 	}
 
 	return nil
 }
 
-// Find finds a document (*struct type) by id
-func (b *BaseModel) Find(id interface{}) (interface{}, error) {
+// Find finds a document (*struct type) by primary key values, or by a key struct
+func (b *BaseModel) Find(ids ...interface{}) (interface{}, error) {
+	args, e := b.toPkArgs(ids)
+	if e != nil {
+		return nil, e
+	}
+	e = b.checkProjection()
+	if e != nil {
+		return nil, e
+	}
+
 	//scan
 	v := reflect.New(b.Type)
 	fieldIndexes, query := b.GetSelectSQL()
@@ -443,19 +809,35 @@ func (b *BaseModel) Find(id interface{}) (interface{}, error) {
 		fieldArgs = append(fieldArgs, v.Elem().Field(i).Addr().Interface())
 	}
 
-	query = query + ` where ` + b.dbTags[0] + `=$1`
-	e := b.Pool.QueryRow(query, id).Scan(fieldArgs...)
+	query = query + b.getPkWhere(0)
+	e = b.queryRow(query, args, fieldArgs...)
 	if e != nil {
 		if e == sql.ErrNoRows {
 			return nil, e
 		}
-		return nil, fmt.Errorf("%w:%s", e, query)
+		return nil, toError(e, query)
+	}
+	if len(b.preloads) > 0 {
+		vs := reflect.MakeSlice(reflect.SliceOf(v.Type()), 0, 1)
+		e = b.preload(reflect.Append(vs, v))
+		if e != nil {
+			return nil, e
+		}
+	}
+	e = afterFind(v)
+	if e != nil {
+		return nil, e
 	}
 	return v.Interface(), nil
 }
 
 // FindWhere finds a document (*struct type) that matches 'where' condition
 func (b *BaseModel) FindWhere(where string, args ...interface{}) (interface{}, error) {
+	e := b.checkProjection()
+	if e != nil {
+		return nil, e
+	}
+
 	//where
 	where = toWhere(where)
 
@@ -467,29 +849,50 @@ func (b *BaseModel) FindWhere(where string, args ...interface{}) (interface{}, e
 	for _, i := range fieldIndexes {
 		fieldArgs = append(fieldArgs, v.Elem().Field(i).Addr().Interface())
 	}
-	e := b.Pool.QueryRow(query, args...).Scan(fieldArgs...)
+	e = b.queryRow(query, args, fieldArgs...)
 	if e != nil {
 		if e == sql.ErrNoRows {
 			return nil, e
 		}
-		return nil, fmt.Errorf("%w:%s", e, query)
+		return nil, toError(e, query)
+	}
+	if len(b.preloads) > 0 {
+		vs := reflect.MakeSlice(reflect.SliceOf(v.Type()), 0, 1)
+		e = b.preload(reflect.Append(vs, v))
+		if e != nil {
+			return nil, e
+		}
+	}
+	e = afterFind(v)
+	if e != nil {
+		return nil, e
 	}
 	return v.Interface(), nil
 }
 
 // QueryWhere queries documents ([]*struct type) that matches 'where' condition
 func (b *BaseModel) QueryWhere(where string, args ...interface{}) (interface{}, error) {
+	e := b.checkProjection()
+	if e != nil {
+		return nil, e
+	}
 	where = toWhere(where)
 
 	fieldIndexes, query := b.GetSelectSQL()
 
 	//query
 	query = query + where
-	rows, e := b.Pool.Query(query, args...)
+	rows, e := b.query(query, args...)
 	if e != nil {
-		return nil, fmt.Errorf("%w:%s", e, query)
+		return nil, toError(e, query)
 	}
 
+	return b.scanModels(rows, fieldIndexes)
+}
+
+// scanModels scans rows into []*T by fieldIndexes, loading preloaded relations before calling AfterFind hooks
+func (b *BaseModel) scanModels(rows *sql.Rows, fieldIndexes []int) (interface{}, error) {
+	var e error
 	vs := reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(b.Type)), 0, 2)
 	for rows.Next() {
 		v := reflect.New(b.Type)
@@ -514,20 +917,36 @@ func (b *BaseModel) QueryWhere(where string, args ...interface{}) (interface{},
 	if e = rows.Err(); e != nil {
 		return nil, e
 	}
+	e = b.preload(vs)
+	if e != nil {
+		return nil, e
+	}
+	for i := 0; i < vs.Len(); i++ {
+		e = afterFind(vs.Index(i))
+		if e != nil {
+			return nil, e
+		}
+	}
 
 	return vs.Interface(), nil
 }
 
-func (b *BaseModel) Exists(id interface{}) (bool, error) {
+// Exists checks if a document exists by primary key values, or by a key struct
+func (b *BaseModel) Exists(ids ...interface{}) (bool, error) {
+	args, e := b.toPkArgs(ids)
+	if e != nil {
+		return false, e
+	}
+
 	//scan
 	num := 0
-	query := `select 1 from ` + b.TableName + ` where ` + b.dbTags[0] + `=$1 limit 1`
-	e := b.Pool.QueryRow(query, id).Scan(&num)
+	query := `select 1 from ` + b.fromTable() + b.getPkWhere(0) + ` limit 1`
+	e = b.queryRow(query, args, &num)
 	if e != nil {
 		if e == sql.ErrNoRows {
 			return false, nil
 		}
-		return false, fmt.Errorf("%w:%s", e, query)
+		return false, toError(e, query)
 	}
 	return num > 0, nil
 }
@@ -538,13 +957,13 @@ func (b *BaseModel) ExistsWhere(where string, args ...interface{}) (bool, error)
 
 	//scan
 	num := 0
-	query := `select 1 from ` + b.TableName + where + ` limit 1`
-	e := b.Pool.QueryRow(query, args...).Scan(&num)
+	query := `select 1 from ` + b.fromTable() + where + ` limit 1`
+	e := b.queryRow(query, args, &num)
 	if e != nil {
 		if e == sql.ErrNoRows {
 			return false, nil
 		}
-		return false, fmt.Errorf("%w:%s", e, query)
+		return false, toError(e, query)
 	}
 	return num > 0, nil
 }
@@ -554,30 +973,100 @@ func (b *BaseModel) CountWhere(where string, args ...interface{}) (int64, error)
 
 	//scan
 	var num int64
-	query := `select count(*) as count from ` + b.TableName + where
-	e := b.Pool.QueryRow(query, args...).Scan(&num)
+	query := `select count(*) as count from ` + b.fromTable() + where
+	e := b.queryRow(query, args, &num)
 	if e != nil {
-		return 0, fmt.Errorf("%w:%s", e, query)
+		return 0, toError(e, query)
 	}
 	return num, nil
 }
 
-func (b *BaseModel) UpdateSet(sets string, where string, args ...interface{}) (int64, error) {
-	where = toWhere(where)
+// Update updates all non-primary-key columns of v (*struct or struct type), matched by its primary keys. Soft deleted rows are skipped unless WithDeleted is used.
+// For models with a `version` field, the version is checked and increased, ErrStaleObject is returned if the row was modified concurrently
+func (b *BaseModel) Update(v interface{}) (int64, error) {
+	//validate
+	value := reflect.ValueOf(v)
+	t := value.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		value = value.Elem()
+	}
+	if t.String() != b.Type.String() {
+		return 0, errors.New("Wrong update type:" + t.String() + " for table " + b.TableName)
+	}
+	value = toSettable(value)
+	e := beforeUpdate(value)
+	if e != nil {
+		return 0, e
+	}
+	e = b.validate(value)
+	if e != nil {
+		return 0, e
+	}
+	b.setAutoTime(value, false)
 
-	query := `update ` + b.TableName + ` set ` + sets + where
-	result, e := b.Pool.Exec(query, args...)
+	//sets
+	sets := []string{}
+	args := []interface{}{}
+	for i, dbTag := range b.dbTags {
+		if b.isPk(i) || b.isReadonly(i) || b.isAutoCreateTime(i) || dbTag == b.softDelete {
+			continue
+		}
+		if i == b.version {
+			sets = append(sets, dbTag+"="+dbTag+"+1")
+			continue
+		}
+		sets = append(sets, dbTag+"=$"+strconv.Itoa(len(args)+1))
+		args = append(args, toArg(b.columnField(value, i)))
+	}
+	if len(sets) == 0 {
+		return 0, errors.New("No column to update for table " + b.TableName)
+	}
+	where := b.getPkWhere(len(args))
+	for _, pk := range b.pks {
+		args = append(args, b.columnField(value, pk).Interface())
+	}
+	if b.version > -1 {
+		where += ` and ` + b.dbTags[b.version] + `=$` + strconv.Itoa(len(args)+1)
+		args = append(args, b.columnField(value, b.version).Interface())
+	}
+	if b.softDelete != "" && !b.withDeleted {
+		where += ` and ` + b.softDelete + ` is null`
+	}
+
+	query := `update ` + b.TableName + ` set ` + strings.Join(sets, ",") + where
+	result, e := b.exec(query, args...)
+	if e != nil {
+		return 0, toError(e, query)
+	}
+	rows, e := result.RowsAffected()
+	if e != nil {
+		return 0, e
+	}
+	if b.version > -1 {
+		if rows == 0 {
+			return 0, fmt.Errorf("%w: table %s, version %v", ErrStaleObject, b.TableName, b.columnField(value, b.version).Interface())
+		}
+		b.increaseVersion(value)
+	}
+	return rows, nil
+}
+
+// UpdateSet updates columns by 'sets' of documents that match 'where' condition, skipping soft deleted rows unless WithDeleted is used. BeforeUpdate hook is not called since there is no struct to update
+func (b *BaseModel) UpdateSet(sets string, where string, args ...interface{}) (int64, error) {
+	query := `update ` + b.TableName + ` set ` + b.toAutoTimeSets(sets) + b.toUpdateWhere(where)
+	result, e := b.exec(query, args...)
 	if e != nil {
-		return 0, fmt.Errorf("%w:%s", e, query)
+		return 0, toError(e, query)
 	}
 	return result.RowsAffected()
 }
 
 func (b *BaseModel) Clear() error {
 	query := `truncate table ` + b.TableName
-	_, e := b.Pool.Exec(query)
+	_, e := b.exec(query)
 	if e != nil {
-		return fmt.Errorf("%w:%s", e, query)
+		return toError(e, query)
 	}
 	return nil
 }
@@ -586,22 +1075,53 @@ func (b *BaseModel) Truncate() error {
 	return b.Clear()
 }
 
-func (b *BaseModel) Delete(id interface{}) (int64, error) {
-	query := `delete from ` + b.TableName + ` where ` + b.dbTags[0] + `=$1`
-	result, e := b.Pool.Exec(query, id)
+// Delete deletes a document by primary key values, or by a key struct. Tables supporting soft delete get the row marked as deleted instead
+func (b *BaseModel) Delete(ids ...interface{}) (int64, error) {
+	if b.softDelete == "" {
+		return b.HardDelete(ids...)
+	}
+	args, e := b.toPkArgs(ids)
+	if e != nil {
+		return 0, e
+	}
+	e = b.beforeDelete(ids)
+	if e != nil {
+		if e == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, e
+	}
+
+	query := `update ` + b.TableName + ` set ` + b.softDelete + `=now()` + b.getPkWhere(0) + ` and ` + b.softDelete + ` is null`
+	result, e := b.exec(query, args...)
 	if e != nil {
-		return 0, fmt.Errorf("%w:%s", e, query)
+		return 0, toError(e, query)
 	}
 	return result.RowsAffected()
 }
 
+// DeleteWhere deletes documents that match 'where' condition. Tables supporting soft delete get the rows marked as deleted instead
 func (b *BaseModel) DeleteWhere(where string, args ...interface{}) (int64, error) {
-	where = toWhere(where)
+	if b.softDelete == "" {
+		return b.HardDeleteWhere(where, args...)
+	}
+	e := b.beforeDeleteWhere(where, args)
+	if e != nil {
+		return 0, e
+	}
 
-	query := `delete from ` + b.TableName + where
-	result, e := b.Pool.Exec(query, args...)
+	query := `update ` + b.TableName + ` set ` + b.softDelete + `=now()` + b.toSoftWhere(where)
+	result, e := b.exec(query, args...)
 	if e != nil {
-		return 0, fmt.Errorf("%w:%s", e, query)
+		return 0, toError(e, query)
 	}
 	return result.RowsAffected()
 }
+
+// toArg converts field to query arg, wrapping slices except []byte with pq.Array
+func toArg(field reflect.Value) interface{} {
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		return pq.Array(field.Interface())
+	}
+	return field.Interface()
+}
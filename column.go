@@ -6,12 +6,35 @@ import (
 )
 
 type Column struct {
-	ColumnName string `db:"column_name"`
-	DataType   string `db:"data_type"`
+	ColumnName           string `db:"column_name"`
+	DataType             string `db:"data_type"`
+	IsNullable           string `db:"is_nullable"`
+	ColumnDefault        string `db:"column_default"`
+	Comment              string `db:"comment"`
+	IsGenerated          string `db:"is_generated"`
+	GenerationExpression string `db:"generation_expression"`
 }
 
+// TableSchema is the full metadata of a remote table
+type TableSchema struct {
+	Schema      string
+	TableName   string
+	Comment     string
+	Columns     []Column
+	Indexes     []IndexSchema
+	Constraints []ConstraintSchema
+	ForeignKeys []ForeignKeySchema
+}
+
+// queryFunc queries rows, like (*sql.DB).Query, or (*BaseModel).query which calls query hooks
+type queryFunc func(query string, args ...interface{}) (*sql.Rows, error)
+
 func DescTable(pool *sql.DB, database, schema, tableName string) ([]Column, error) {
-	rows, e := pool.Query(`select column_name,data_type from information_schema.columns where table_catalog=$1 and table_schema=$2 and table_name=$3`, database, schema, tableName)
+	return descTable(pool.Query, database, schema, tableName)
+}
+
+func descTable(query queryFunc, database, schema, tableName string) ([]Column, error) {
+	rows, e := query(`select column_name,data_type,is_nullable,coalesce(column_default,''),coalesce(col_description(to_regclass(quote_ident(table_schema)||'.'||quote_ident(table_name)),ordinal_position),''),is_generated,coalesce(generation_expression,'') from information_schema.columns where table_catalog=$1 and table_schema=$2 and table_name=$3 order by ordinal_position`, database, schema, tableName)
 	if e != nil {
 		return nil, e
 	}
@@ -19,7 +42,7 @@ func DescTable(pool *sql.DB, database, schema, tableName string) ([]Column, erro
 	out := []Column{}
 	for rows.Next() {
 		v := Column{}
-		e = rows.Scan(&v.ColumnName, &v.DataType)
+		e = rows.Scan(&v.ColumnName, &v.DataType, &v.IsNullable, &v.ColumnDefault, &v.Comment, &v.IsGenerated, &v.GenerationExpression)
 		if e != nil {
 			break
 		}
@@ -39,3 +62,61 @@ func DescTable(pool *sql.DB, database, schema, tableName string) ([]Column, erro
 
 	return out, nil
 }
+
+// DescTableSchema returns columns, indexes, constraints and comments of schema.tableName, or nil if the table doesn't exist
+func DescTableSchema(pool *sql.DB, database, schema, tableName string) (*TableSchema, error) {
+	return descTableSchema(pool.Query, database, schema, tableName)
+}
+
+func descTableSchema(query queryFunc, database, schema, tableName string) (*TableSchema, error) {
+	v := &TableSchema{
+		Schema:    schema,
+		TableName: tableName,
+	}
+	rows, e := query(`select obj_description(c.oid,'pg_class') from pg_class c join pg_namespace n on n.oid=c.relnamespace where n.nspname=$1 and c.relname=$2`, schema, tableName)
+	if e != nil {
+		return nil, e
+	}
+	found := false
+	var comment sql.NullString
+	if rows.Next() {
+		found = true
+		e = rows.Scan(&comment)
+	}
+	if closeErr := rows.Close(); closeErr != nil {
+		return nil, fmt.Errorf("rows.Close() err:%w", closeErr)
+	}
+	if e != nil {
+		return nil, e
+	}
+	if e = rows.Err(); e != nil {
+		return nil, e
+	}
+	if !found {
+		return nil, nil
+	}
+	v.Comment = comment.String
+
+	v.Columns, e = descTable(query, database, schema, tableName)
+	if e != nil {
+		return nil, e
+	}
+	v.Indexes, e = descIndexes(query, schema, tableName)
+	if e != nil {
+		return nil, e
+	}
+	v.Constraints, e = descConstraints(query, schema, tableName)
+	if e != nil {
+		return nil, e
+	}
+	v.ForeignKeys, e = descForeignKeys(query, database, schema, tableName)
+	if e != nil {
+		return nil, e
+	}
+	return v, nil
+}
+
+// Desc returns full metadata of the model's remote table
+func (b *BaseModel) Desc() (*TableSchema, error) {
+	return descTableSchema(b.query, b.Database, b.Schema, b.TableName)
+}
@@ -0,0 +1,167 @@
+package pgx
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/StevenZack/tools/strToolkit"
+	"github.com/lib/pq"
+)
+
+type (
+	// Constraint is a named table constraint, Def is like "check (start_at < end_at)", "unique (a,b) deferrable initially deferred" or "exclude using gist (room with =, during with &&)"
+	Constraint struct {
+		Name string
+		Def  string
+	}
+	// ConstraintProvider is implemented by models declaring table-level constraints
+	ConstraintProvider interface {
+		Constraints() []Constraint
+	}
+	ConstraintSchema struct {
+		ConstraintName string   `db:"conname"`
+		ConstraintType string   `db:"contype"`
+		ConstraintDef  string   `db:"condef"`
+		Columns        []string `db:"columns"`
+	}
+)
+
+func (c *Constraint) ToConstraintDef() string {
+	return `constraint ` + c.Name + ` ` + c.Def
+}
+
+func toConstraints(t reflect.Type) ([]Constraint, error) {
+	provider, ok := reflect.New(t).Interface().(ConstraintProvider)
+	if !ok {
+		return nil, nil
+	}
+	constraints := provider.Constraints()
+	names := make(map[string]bool)
+	for _, c := range constraints {
+		if c.Name == "" || c.Def == "" {
+			return nil, errors.New("constraint name and def must be set:" + c.Name + " " + c.Def)
+		}
+		if names[c.Name] {
+			return nil, errors.New("duplicated constraint name:" + c.Name)
+		}
+		names[c.Name] = true
+	}
+	return constraints, nil
+}
+
+// isImplicitConstraint reports whether remote is the column-level check declared by ToPostgreType on unsigned columns, which is not managed by Constraints()
+func (b *BaseModel) isImplicitConstraint(remote ConstraintSchema) bool {
+	if remote.ConstraintType != "c" || len(remote.Columns) != 1 {
+		return false
+	}
+	c := b.columnIndex(remote.Columns[0])
+	if c == -1 {
+		return false
+	}
+	check := strToolkit.SubAfter(b.pgTypes[c], " check ", "")
+	if check == "" {
+		return false
+	}
+	return normalizeCheckDef("check "+check) == normalizeCheckDef(remote.ConstraintDef)
+}
+
+// normalizeCheckDef reduces both pg_get_constraintdef output and local check definitions to a comparable form
+func normalizeCheckDef(def string) string {
+	return strings.ReplaceAll(normalizeIndexDef(def), "'", "")
+}
+
+// renderConstraintDef returns Postgres's own rendering of c, added to an empty temporary copy of the table in a rolled back transaction
+func (b *BaseModel) renderConstraintDef(c Constraint) (string, error) {
+	tx, e := b.Pool.BeginTx(b.context(), nil)
+	if e != nil {
+		return "", e
+	}
+	defer tx.Rollback()
+
+	table := `pg_temp.pgx_constraint_def`
+	for _, query := range []string{
+		`create temporary table pgx_constraint_def (like ` + b.Schema + `.` + b.TableName + `) on commit drop`,
+		`alter table ` + table + ` add ` + c.ToConstraintDef(),
+	} {
+		e = b.run(query, nil, func() (int64, error) {
+			_, e := tx.ExecContext(b.context(), query)
+			return 0, e
+		})
+		if e != nil {
+			return "", fmt.Errorf("%w:%s", e, query)
+		}
+	}
+
+	var def string
+	query := `select pg_get_constraintdef(oid) from pg_constraint where conrelid=to_regclass($1) and conname=$2`
+	e = b.queryRowTx(tx, query, []interface{}{table, c.Name}, &def)
+	if e != nil {
+		return "", fmt.Errorf("%w:%s", e, query)
+	}
+	return def, nil
+}
+
+// constraintDefEqual compares remoteDef, the pg_get_constraintdef of the remote constraint, with c.
+// Definitions differing in text are rendered by Postgres before comparing again, ones that can't be rendered are reported equal
+func (b *BaseModel) constraintDefEqual(remoteDef string, c Constraint) bool {
+	if normalizeCheckDef(remoteDef) == normalizeCheckDef(c.Def) {
+		return true
+	}
+	def, e := b.renderConstraintDef(c)
+	if e != nil {
+		b.log(LevelWarn, "constraint definition check skipped", Field{Key: "constraint", Value: c.Name}, Field{Key: "error", Value: e})
+		return true
+	}
+	return normalizeCheckDef(remoteDef) == normalizeCheckDef(def)
+}
+
+func (b *BaseModel) addConstraint(c Constraint) error {
+	query := `alter table ` + b.Schema + `.` + b.TableName + ` add ` + c.ToConstraintDef()
+	_, e := b.exec(query)
+	if e != nil {
+		return fmt.Errorf("%w:%s", e, query)
+	}
+	return nil
+}
+
+// GetConstraints returns check, unique and exclusion constraints of the table
+func (b *BaseModel) GetConstraints() ([]ConstraintSchema, error) {
+	return descConstraints(b.query, b.Schema, b.TableName)
+}
+
+// DescConstraints returns check, unique and exclusion constraints of schema.tableName
+func DescConstraints(pool *sql.DB, schema, tableName string) ([]ConstraintSchema, error) {
+	return descConstraints(pool.Query, schema, tableName)
+}
+
+func descConstraints(query queryFunc, schema, tableName string) ([]ConstraintSchema, error) {
+	rows, e := query(`select c.conname,c.contype::text,pg_get_constraintdef(c.oid),array(select a.attname::text from pg_attribute a where a.attrelid=c.conrelid and a.attnum=any(c.conkey)) from pg_constraint c join pg_class t on t.oid=c.conrelid join pg_namespace n on n.oid=t.relnamespace where n.nspname=$1 and t.relname=$2 and c.contype in ('c','u','x')`, schema, tableName)
+	if e != nil {
+		return nil, e
+	}
+
+	vs := []ConstraintSchema{}
+	for rows.Next() {
+		v := ConstraintSchema{}
+		e = rows.Scan(&v.ConstraintName, &v.ConstraintType, &v.ConstraintDef, pq.Array(&v.Columns))
+		if e != nil {
+			break
+		}
+		vs = append(vs, v)
+	}
+	//check err
+	if closeErr := rows.Close(); closeErr != nil {
+		return nil, fmt.Errorf("rows.Close() err:%w", closeErr)
+	}
+	if e != nil {
+		return nil, e
+	}
+	if e = rows.Err(); e != nil {
+		return nil, e
+	}
+
+	return vs, nil
+}
@@ -0,0 +1,57 @@
+package pgx
+
+import "testing"
+
+func TestIsImplicitConstraint(t *testing.T) {
+	b := &BaseModel{
+		dbTags:  []string{"id", "price", "total"},
+		pgTypes: []string{"bigserial", "bigint not null default 0 check ( price>-1 )", "bigint not null default 0"},
+	}
+	tests := []struct {
+		remote   ConstraintSchema
+		implicit bool
+	}{
+		{
+			remote:   ConstraintSchema{ConstraintName: "orders_price_check", ConstraintType: "c", ConstraintDef: "CHECK ((price > '-1'::integer))", Columns: []string{"price"}},
+			implicit: true,
+		},
+		{
+			remote:   ConstraintSchema{ConstraintName: "orders_total_check", ConstraintType: "c", ConstraintDef: "CHECK ((total > 0))", Columns: []string{"total"}},
+			implicit: false,
+		},
+		{
+			remote:   ConstraintSchema{ConstraintName: "orders_price_check1", ConstraintType: "c", ConstraintDef: "CHECK ((price < 100))", Columns: []string{"price"}},
+			implicit: false,
+		},
+		{
+			remote:   ConstraintSchema{ConstraintName: "orders_price_total_check", ConstraintType: "c", ConstraintDef: "CHECK ((price > '-1'::integer))", Columns: []string{"price", "total"}},
+			implicit: false,
+		},
+		{
+			remote:   ConstraintSchema{ConstraintName: "orders_price_key", ConstraintType: "u", ConstraintDef: "UNIQUE (price)", Columns: []string{"price"}},
+			implicit: false,
+		},
+	}
+	for _, test := range tests {
+		if got := b.isImplicitConstraint(test.remote); got != test.implicit {
+			t.Errorf("isImplicitConstraint(%s) = %v, want %v", test.remote.ConstraintName, got, test.implicit)
+		}
+	}
+}
+
+func TestConstraintDefEqual(t *testing.T) {
+	b := &BaseModel{}
+	tests := []struct {
+		remote string
+		local  Constraint
+	}{
+		{remote: "CHECK ((start_at < end_at))", local: Constraint{Name: "start_before_end", Def: "check (start_at < end_at)"}},
+		{remote: "UNIQUE (a, b) DEFERRABLE INITIALLY DEFERRED", local: Constraint{Name: "a_b_key", Def: "unique (a,b) deferrable initially deferred"}},
+		{remote: "CHECK (((status)::text <> 'x'::text))", local: Constraint{Name: "status_check", Def: "check (status <> 'x')"}},
+	}
+	for _, test := range tests {
+		if !b.constraintDefEqual(test.remote, test.local) {
+			t.Errorf("constraintDefEqual(%q, %q) is false", test.remote, test.local.Def)
+		}
+	}
+}
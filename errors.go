@@ -0,0 +1,87 @@
+package pgx
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned by Find and FindWhere when no row matches, it's the same value as sql.ErrNoRows
+	ErrNotFound            = sql.ErrNoRows
+	ErrUniqueViolation     = errors.New("unique violation")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrNotNullViolation    = errors.New("not null violation")
+	ErrCheckViolation      = errors.New("check violation")
+)
+
+// ConstraintError is a classified constraint violation, usable with errors.Is(e, ErrUniqueViolation) etc. and errors.As(e, **pq.Error).
+// Column is the violating column, comma separated for multi-column unique and foreign keys
+type ConstraintError struct {
+	Kind       error
+	Table      string
+	Column     string
+	Constraint string
+	Detail     string
+	Query      string
+	Err        *pq.Error
+}
+
+func (c *ConstraintError) Error() string {
+	return fmt.Sprintf("%v: table %s, column %s, constraint %s: %v:%s", c.Kind, c.Table, c.Column, c.Constraint, c.Err, c.Query)
+}
+
+func (c *ConstraintError) Is(target error) bool {
+	return target == c.Kind
+}
+
+func (c *ConstraintError) Unwrap() error {
+	return c.Err
+}
+
+// toError classifies constraint violations of e, other errors are wrapped with the query appended
+func toError(e error, query string) error {
+	var pqErr *pq.Error
+	if !errors.As(e, &pqErr) {
+		return fmt.Errorf("%w:%s", e, query)
+	}
+
+	var kind error
+	switch pqErr.Code {
+	case "23505":
+		kind = ErrUniqueViolation
+	case "23503":
+		kind = ErrForeignKeyViolation
+	case "23502":
+		kind = ErrNotNullViolation
+	case "23514":
+		kind = ErrCheckViolation
+	default:
+		return fmt.Errorf("%w:%s", e, query)
+	}
+	column := pqErr.Column
+	if column == "" {
+		column = toDetailColumn(pqErr.Detail)
+	}
+	return &ConstraintError{
+		Kind:       kind,
+		Table:      pqErr.Table,
+		Column:     column,
+		Constraint: pqErr.Constraint,
+		Detail:     pqErr.Detail,
+		Query:      query,
+		Err:        pqErr,
+	}
+}
+
+// toDetailColumn parses key columns from detail of unique and foreign key violations, like "Key (a, b)=(1, 2) already exists."
+func toDetailColumn(detail string) string {
+	if !strings.HasPrefix(detail, "Key (") || !strings.Contains(detail, ")=(") {
+		return ""
+	}
+	columns := strings.Split(detail[len("Key ("):strings.Index(detail, ")=(")], ", ")
+	return strings.Join(columns, ",")
+}
@@ -0,0 +1,22 @@
+package pgx
+
+import "testing"
+
+func TestToDetailColumn(t *testing.T) {
+	tests := []struct {
+		detail string
+		column string
+	}{
+		{detail: "Key (email)=(a@b.com) already exists.", column: "email"},
+		{detail: "Key (org_id, name)=(1, a) already exists.", column: "org_id,name"},
+		{detail: `Key (user_id)=(5) is not present in table "users".`, column: "user_id"},
+		{detail: "Key (lower(email::text))=(a@b.com) already exists.", column: "lower(email::text)"},
+		{detail: "Failing row contains (1, null).", column: ""},
+		{detail: "", column: ""},
+	}
+	for _, test := range tests {
+		if got := toDetailColumn(test.detail); got != test.column {
+			t.Errorf("toDetailColumn(%q) = %q, want %q", test.detail, got, test.column)
+		}
+	}
+}
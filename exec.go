@@ -0,0 +1,114 @@
+package pgx
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// WithContext returns a copy of b whose statements run with ctx, which is also passed to query hooks so spans attach to the caller's trace
+func (b *BaseModel) WithContext(ctx context.Context) *BaseModel {
+	c := *b
+	c.ctx = ctx
+	return &c
+}
+
+// context returns the context set by WithContext, context.Background() by default
+func (b *BaseModel) context() context.Context {
+	if b.ctx == nil {
+		return context.Background()
+	}
+	return b.ctx
+}
+
+// exec executes a statement on the pool
+func (b *BaseModel) exec(query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	e := b.run(query, args, func() (int64, error) {
+		var e error
+		result, e = b.Pool.ExecContext(b.context(), query, args...)
+		if e != nil {
+			return -1, e
+		}
+		rows, _ := result.RowsAffected()
+		return rows, nil
+	})
+	return result, e
+}
+
+// queryRow queries a single row and scans it into dest, sql.ErrNoRows is returned but reported to hooks as 0 rows rather than an error
+func (b *BaseModel) queryRow(query string, args []interface{}, dest ...interface{}) error {
+	return b.runRow(query, args, func() *sql.Row {
+		return b.Pool.QueryRowContext(b.context(), query, args...)
+	}, dest)
+}
+
+// queryRowTx queries a single row in tx and scans it into dest, like queryRow
+func (b *BaseModel) queryRowTx(tx *sql.Tx, query string, args []interface{}, dest ...interface{}) error {
+	return b.runRow(query, args, func() *sql.Row {
+		return tx.QueryRowContext(b.context(), query, args...)
+	}, dest)
+}
+
+// runRow scans the row returned by fn into dest
+func (b *BaseModel) runRow(query string, args []interface{}, fn func() *sql.Row, dest []interface{}) error {
+	noRows := false
+	e := b.run(query, args, func() (int64, error) {
+		e := fn().Scan(dest...)
+		if e == sql.ErrNoRows {
+			noRows = true
+			return 0, nil
+		}
+		if e != nil {
+			return 0, e
+		}
+		return 1, nil
+	})
+	if noRows {
+		return sql.ErrNoRows
+	}
+	return e
+}
+
+// query queries rows, the rows count is unknown until they're iterated
+func (b *BaseModel) query(query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	e := b.run(query, args, func() (int64, error) {
+		var e error
+		rows, e = b.Pool.QueryContext(b.context(), query, args...)
+		return -1, e
+	})
+	return rows, e
+}
+
+// run runs fn which executes query, calling query hooks around it and logging its duration and rows
+func (b *BaseModel) run(query string, args []interface{}, fn func() (int64, error)) error {
+	event := &QueryEvent{
+		Table: b.TableName,
+		Query: query,
+		Args:  args,
+		Start: time.Now(),
+	}
+	ctxs := make([]context.Context, len(b.QueryHooks))
+	for i, hook := range b.QueryHooks {
+		ctxs[i] = hook.BeforeQuery(b.context(), event)
+	}
+
+	event.Rows, event.Err = fn()
+	event.Duration = time.Since(event.Start)
+
+	fields := []Field{
+		{Key: "query", Value: query},
+		{Key: "duration", Value: event.Duration},
+		{Key: "rows", Value: event.Rows},
+	}
+	if event.Err != nil {
+		fields = append(fields, Field{Key: "error", Value: event.Err})
+	}
+	b.log(LevelDebug, "query", fields...)
+
+	for i := len(b.QueryHooks) - 1; i > -1; i-- {
+		b.QueryHooks[i].AfterQuery(ctxs[i], event)
+	}
+	return event.Err
+}
@@ -0,0 +1,217 @@
+package pgx
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/StevenZack/tools/strToolkit"
+)
+
+type (
+	foreignKey struct {
+		column    string
+		refTable  string
+		refColumn string
+		onDelete  string
+		onUpdate  string
+	}
+	ForeignKeySchema struct {
+		ConstraintName string `db:"constraint_name"`
+		UpdateRule     string `db:"update_rule"`
+		DeleteRule     string `db:"delete_rule"`
+		RefSchema      string `db:"ref_schema"`
+		RefTable       string `db:"ref_table"`
+		RefColumn      string `db:"ref_column"`
+	}
+)
+
+// toForeignKey parses fk tag with format like: "users.id,on_delete=cascade,on_update=restrict"
+func toForeignKey(column, tag string) (foreignKey, error) {
+	fk := foreignKey{
+		column:   column,
+		onDelete: "NO ACTION",
+		onUpdate: "NO ACTION",
+	}
+	ref := strToolkit.SubBefore(tag, ",", tag)
+	fk.refTable = strToolkit.SubBeforeLast(ref, ".", "")
+	fk.refColumn = strToolkit.SubAfterLast(ref, ".", "")
+	if fk.refTable == "" || fk.refColumn == "" {
+		return fk, errors.New("field '" + column + "', invalid fk tag format:" + tag)
+	}
+
+	vs, e := url.ParseQuery(strings.ReplaceAll(strToolkit.SubAfter(tag, ",", ""), ",", "&"))
+	if e != nil {
+		return fk, errors.New("field '" + column + "', invalid fk tag format:" + tag)
+	}
+	for k := range vs {
+		action, e := toReferentialAction(vs.Get(k))
+		if e != nil {
+			return fk, errors.New("field '" + column + "', " + e.Error())
+		}
+		switch k {
+		case "on_delete":
+			fk.onDelete = action
+		case "on_update":
+			fk.onUpdate = action
+		default:
+			return fk, errors.New("field '" + column + "', unsupported fk key:" + k)
+		}
+	}
+	return fk, nil
+}
+
+func toReferentialAction(s string) (string, error) {
+	action := strings.ToUpper(strings.ReplaceAll(s, "_", " "))
+	switch action {
+	case "CASCADE", "RESTRICT", "SET NULL", "SET DEFAULT", "NO ACTION":
+		return action, nil
+	}
+	return "", errors.New("unsupported referential action:" + s)
+}
+
+func (f *foreignKey) ToConstraintName(tableName string) string {
+	return tableName + "_" + f.column + "_fkey"
+}
+
+func (f *foreignKey) ToConstraintDef(tableName string) string {
+	return `constraint ` + f.ToConstraintName(tableName) + ` foreign key (` + f.column + `) references ` + f.refTable + ` (` + f.refColumn + `) on delete ` + f.onDelete + ` on update ` + f.onUpdate
+}
+
+// equal reports whether remote references the same column with the same rules as f, refTable without schema is in schema
+func (f *foreignKey) equal(remote ForeignKeySchema, schema string) bool {
+	refSchema := strToolkit.SubBeforeLast(f.refTable, ".", schema)
+	refTable := strToolkit.SubAfterLast(f.refTable, ".", f.refTable)
+	return remote.RefSchema == refSchema && remote.RefTable == refTable && remote.RefColumn == f.refColumn &&
+		remote.DeleteRule == f.onDelete && remote.UpdateRule == f.onUpdate
+}
+
+func (b *BaseModel) addForeignKey(fk foreignKey) error {
+	query := `alter table ` + b.Schema + `.` + b.TableName + ` add ` + fk.ToConstraintDef(b.TableName)
+	_, e := b.exec(query)
+	if e != nil {
+		return fmt.Errorf("%w:%s", e, query)
+	}
+	return nil
+}
+
+func (b *BaseModel) dropConstraint(name string) error {
+	query := `alter table ` + b.Schema + `.` + b.TableName + ` drop constraint ` + name
+	_, e := b.exec(query)
+	if e != nil {
+		return fmt.Errorf("%w:%s", e, query)
+	}
+	return nil
+}
+
+func (b *BaseModel) GetForeignKeys() ([]ForeignKeySchema, error) {
+	return descForeignKeys(b.query, b.Database, b.Schema, b.TableName)
+}
+
+// toRuleSQL converts pg_constraint's referential action code column to its name, like information_schema.referential_constraints does
+func toRuleSQL(column string) string {
+	return `case ` + column + ` when 'r' then 'RESTRICT' when 'c' then 'CASCADE' when 'n' then 'SET NULL' when 'd' then 'SET DEFAULT' else 'NO ACTION' end`
+}
+
+func DescForeignKeys(pool *sql.DB, database, schema, tableName string) ([]ForeignKeySchema, error) {
+	return descForeignKeys(pool.Query, database, schema, tableName)
+}
+
+func descForeignKeys(query queryFunc, database, schema, tableName string) ([]ForeignKeySchema, error) {
+	rows, e := query(`select c.conname,`+toRuleSQL("c.confupdtype")+`,`+toRuleSQL("c.confdeltype")+`,rn.nspname,r.relname,a.attname from pg_constraint c join pg_class t on t.oid=c.conrelid join pg_namespace n on n.oid=t.relnamespace join pg_class r on r.oid=c.confrelid join pg_namespace rn on rn.oid=r.relnamespace join pg_attribute a on a.attrelid=c.confrelid and a.attnum=c.confkey[1] where current_database()=$1 and n.nspname=$2 and t.relname=$3 and c.contype='f'`, database, schema, tableName)
+	if e != nil {
+		return nil, e
+	}
+
+	vs := []ForeignKeySchema{}
+	for rows.Next() {
+		v := ForeignKeySchema{}
+		e = rows.Scan(&v.ConstraintName, &v.UpdateRule, &v.DeleteRule, &v.RefSchema, &v.RefTable, &v.RefColumn)
+		if e != nil {
+			break
+		}
+		vs = append(vs, v)
+	}
+	//check err
+	if closeErr := rows.Close(); closeErr != nil {
+		return nil, fmt.Errorf("rows.Close() err:%w", closeErr)
+	}
+	if e != nil {
+		return nil, e
+	}
+	if e = rows.Err(); e != nil {
+		return nil, e
+	}
+
+	return vs, nil
+}
+
+// NewBaseModels creates models for datas, creating referenced tables before the tables referencing them
+func NewBaseModels(dsn string, datas []interface{}, opts ...Option) ([]*BaseModel, error) {
+	order, e := sortByForeignKey(datas)
+	if e != nil {
+		return nil, e
+	}
+
+	models := make([]*BaseModel, len(datas))
+	for _, i := range order {
+		models[i], e = NewBaseModel(dsn, datas[i], opts...)
+		if e != nil {
+			return nil, e
+		}
+	}
+	return models, nil
+}
+
+// sortByForeignKey returns indexes of datas, ordered so that referenced tables come first
+func sortByForeignKey(datas []interface{}) ([]int, error) {
+	tables := make(map[string]int)
+	for i, data := range datas {
+		tables[ToTableName(reflect.TypeOf(data).Name())] = i
+	}
+
+	order := []int{}
+	states := make(map[int]int) // 1:visiting, 2:visited
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch states[i] {
+		case 1:
+			return errors.New("circular foreign key reference found at " + reflect.TypeOf(datas[i]).String())
+		case 2:
+			return nil
+		}
+		states[i] = 1
+		t := reflect.TypeOf(datas[i])
+		if t.Kind() != reflect.Struct {
+			return errors.New("data must be struct type")
+		}
+		for j := 0; j < t.NumField(); j++ {
+			tag, ok := t.Field(j).Tag.Lookup("fk")
+			if !ok {
+				continue
+			}
+			refTable := strToolkit.SubBeforeLast(strToolkit.SubBefore(tag, ",", tag), ".", "")
+			refTable = strToolkit.SubAfterLast(refTable, ".", refTable)
+			ref, ok := tables[refTable]
+			if !ok || ref == i {
+				continue
+			}
+			if e := visit(ref); e != nil {
+				return e
+			}
+		}
+		states[i] = 2
+		order = append(order, i)
+		return nil
+	}
+
+	for i := range datas {
+		if e := visit(i); e != nil {
+			return nil, e
+		}
+	}
+	return order, nil
+}
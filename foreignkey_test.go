@@ -0,0 +1,111 @@
+package pgx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToForeignKey(t *testing.T) {
+	tests := []struct {
+		tag string
+		fk  foreignKey
+		err bool
+	}{
+		{tag: "users.id", fk: foreignKey{column: "user_id", refTable: "users", refColumn: "id", onDelete: "NO ACTION", onUpdate: "NO ACTION"}},
+		{tag: "auth.users.id,on_delete=cascade", fk: foreignKey{column: "user_id", refTable: "auth.users", refColumn: "id", onDelete: "CASCADE", onUpdate: "NO ACTION"}},
+		{tag: "users.id,on_delete=set_null,on_update=restrict", fk: foreignKey{column: "user_id", refTable: "users", refColumn: "id", onDelete: "SET NULL", onUpdate: "RESTRICT"}},
+		{tag: "users", err: true},
+		{tag: "users.id,on_delete=drop", err: true},
+		{tag: "users.id,on_insert=cascade", err: true},
+	}
+	for _, test := range tests {
+		fk, e := toForeignKey("user_id", test.tag)
+		if (e != nil) != test.err {
+			t.Errorf("toForeignKey(%q) error = %v, want error %v", test.tag, e, test.err)
+			continue
+		}
+		if !test.err && fk != test.fk {
+			t.Errorf("toForeignKey(%q) = %+v, want %+v", test.tag, fk, test.fk)
+		}
+	}
+}
+
+func TestForeignKeyEqual(t *testing.T) {
+	fk := foreignKey{column: "user_id", refTable: "users", refColumn: "id", onDelete: "CASCADE", onUpdate: "NO ACTION"}
+	remote := ForeignKeySchema{ConstraintName: "orders_user_id_fkey", UpdateRule: "NO ACTION", DeleteRule: "CASCADE", RefSchema: "public", RefTable: "users", RefColumn: "id"}
+	tests := []struct {
+		edit  func(r *ForeignKeySchema)
+		equal bool
+	}{
+		{edit: func(r *ForeignKeySchema) {}, equal: true},
+		{edit: func(r *ForeignKeySchema) { r.RefTable = "accounts" }, equal: false},
+		{edit: func(r *ForeignKeySchema) { r.RefColumn = "uid" }, equal: false},
+		{edit: func(r *ForeignKeySchema) { r.RefSchema = "auth" }, equal: false},
+		{edit: func(r *ForeignKeySchema) { r.DeleteRule = "NO ACTION" }, equal: false},
+	}
+	for i, test := range tests {
+		r := remote
+		test.edit(&r)
+		if got := fk.equal(r, "public"); got != test.equal {
+			t.Errorf("#%d equal(%+v) = %v, want %v", i, r, got, test.equal)
+		}
+	}
+
+	qualified := foreignKey{column: "user_id", refTable: "auth.users", refColumn: "id", onDelete: "CASCADE", onUpdate: "NO ACTION"}
+	remote.RefSchema = "auth"
+	if !qualified.equal(remote, "public") {
+		t.Errorf("equal(%+v) with schema qualified ref = false, want true", remote)
+	}
+}
+
+type (
+	fkUser struct {
+		ID uint64 `db:"id"`
+	}
+	fkOrder struct {
+		ID     uint64 `db:"id"`
+		UserID uint64 `db:"user_id" fk:"fk_user.id"`
+	}
+	fkItem struct {
+		ID      uint64 `db:"id"`
+		OrderID uint64 `db:"order_id" fk:"public.fk_order.id,on_delete=cascade"`
+		UserID  uint64 `db:"user_id" fk:"fk_user.id"`
+	}
+	fkNode struct {
+		ID       uint64 `db:"id"`
+		ParentID uint64 `db:"parent_id" fk:"fk_node.id"`
+	}
+	fkA struct {
+		ID  uint64 `db:"id"`
+		BID uint64 `db:"b_id" fk:"fk_b.id"`
+	}
+	fkB struct {
+		ID  uint64 `db:"id"`
+		AID uint64 `db:"a_id" fk:"fk_a.id"`
+	}
+)
+
+func TestSortByForeignKey(t *testing.T) {
+	tests := []struct {
+		datas []interface{}
+		order []int
+		err   bool
+	}{
+		{datas: []interface{}{fkItem{}, fkOrder{}, fkUser{}}, order: []int{2, 1, 0}},
+		{datas: []interface{}{fkUser{}, fkOrder{}, fkItem{}}, order: []int{0, 1, 2}},
+		{datas: []interface{}{fkOrder{}}, order: []int{0}},
+		{datas: []interface{}{fkNode{}}, order: []int{0}},
+		{datas: []interface{}{fkA{}, fkB{}}, err: true},
+		{datas: []interface{}{&fkUser{}}, err: true},
+	}
+	for _, test := range tests {
+		order, e := sortByForeignKey(test.datas)
+		if (e != nil) != test.err {
+			t.Errorf("sortByForeignKey(%T) error = %v, want error %v", test.datas, e, test.err)
+			continue
+		}
+		if !test.err && !reflect.DeepEqual(order, test.order) {
+			t.Errorf("sortByForeignKey(%v) = %v, want %v", test.datas, order, test.order)
+		}
+	}
+}
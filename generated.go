@@ -0,0 +1,102 @@
+package pgx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/StevenZack/tools/strToolkit"
+)
+
+const generatedKeyword = " generated always as "
+
+// toGeneratedType converts pgType to a stored generated column type computed by expr, dropping its default value
+func toGeneratedType(pgType, expr string) string {
+	pgType = strToolkit.SubBefore(pgType, " not null", pgType)
+	pgType = strToolkit.SubBefore(pgType, " default", pgType)
+	return pgType + generatedKeyword + "(" + expr + ") stored"
+}
+
+// isGenerated reports whether the i-th column is a generated column
+func (b *BaseModel) isGenerated(i int) bool {
+	return strings.Contains(b.pgTypes[i], generatedKeyword)
+}
+
+// isReadonly reports whether the i-th column is never written by inserts and updates, like generated columns
+func (b *BaseModel) isReadonly(i int) bool {
+	for _, c := range b.readonly {
+		if c == i {
+			return true
+		}
+	}
+	return false
+}
+
+// generatedExpr returns the expression of the i-th column, or "" if it's not generated
+func (b *BaseModel) generatedExpr(i int) string {
+	if !b.isGenerated(i) {
+		return ""
+	}
+	expr := strToolkit.SubAfter(b.pgTypes[i], generatedKeyword+"(", "")
+	return strToolkit.SubBeforeLast(expr, ") stored", expr)
+}
+
+// renderGeneratedExpr returns Postgres's own rendering of the i-th column's expression, added to an empty temporary copy of the table in a rolled back transaction
+func (b *BaseModel) renderGeneratedExpr(i int) (string, error) {
+	tx, e := b.Pool.BeginTx(b.context(), nil)
+	if e != nil {
+		return "", e
+	}
+	defer tx.Rollback()
+
+	table := `pg_temp.pgx_generated_def`
+	for _, query := range []string{
+		`create temporary table pgx_generated_def (like ` + b.Schema + `.` + b.TableName + `) on commit drop`,
+		`alter table ` + table + ` drop column ` + b.dbTags[i] + `, add column ` + b.dbTags[i] + ` ` + b.pgTypes[i],
+	} {
+		e = b.run(query, nil, func() (int64, error) {
+			_, e := tx.ExecContext(b.context(), query)
+			return 0, e
+		})
+		if e != nil {
+			return "", fmt.Errorf("%w:%s", e, query)
+		}
+	}
+
+	var expr string
+	query := `select pg_get_expr(d.adbin,d.adrelid) from pg_attrdef d join pg_attribute a on a.attrelid=d.adrelid and a.attnum=d.adnum where d.adrelid=to_regclass($1) and a.attname=$2`
+	e = b.queryRowTx(tx, query, []interface{}{table, b.dbTags[i]}, &expr)
+	if e != nil {
+		return "", fmt.Errorf("%w:%s", e, query)
+	}
+	return expr, nil
+}
+
+// generatedExprEqual compares remoteExpr, the generation_expression of the remote column, with the i-th column's expression.
+// Expressions differing in text are rendered by Postgres before comparing again, ones that can't be rendered are reported equal
+func (b *BaseModel) generatedExprEqual(remoteExpr string, i int) bool {
+	if normalizeCheckDef(remoteExpr) == normalizeCheckDef(b.generatedExpr(i)) {
+		return true
+	}
+	expr, e := b.renderGeneratedExpr(i)
+	if e != nil {
+		b.log(LevelWarn, "generated expression check skipped", Field{Key: "column", Value: b.dbTags[i]}, Field{Key: "error", Value: e})
+		return true
+	}
+	return normalizeCheckDef(remoteExpr) == normalizeCheckDef(expr)
+}
+
+// dropColumnExpression turns a generated column into a normal one, which requires PostgreSQL 13 or later
+func (b *BaseModel) dropColumnExpression(name string) error {
+	var version int
+	query := `select current_setting('server_version_num')::int`
+	e := b.queryRow(query, nil, &version)
+	if e != nil {
+		return fmt.Errorf("%w:%s", e, query)
+	}
+	if version < 130000 {
+		return errors.New("Found remote column " + name + " is generated but local field is not, dropping its expression requires PostgreSQL 13 or later, drop the column to recreate it")
+	}
+	_, e = b.exec(`alter table ` + b.Schema + `.` + b.TableName + ` alter column ` + name + ` drop expression`)
+	return e
+}
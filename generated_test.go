@@ -0,0 +1,30 @@
+package pgx
+
+import "testing"
+
+func TestGeneratedExpr(t *testing.T) {
+	b := &BaseModel{
+		dbTags: []string{"price", "total", "name"},
+		pgTypes: []string{
+			"bigint not null default 0",
+			toGeneratedType("bigint not null default 0", "price*(qty+1)"),
+			"text not null default ''",
+		},
+	}
+	tests := []struct {
+		i    int
+		expr string
+	}{
+		{i: 0, expr: ""},
+		{i: 1, expr: "price*(qty+1)"},
+		{i: 2, expr: ""},
+	}
+	for _, test := range tests {
+		if expr := b.generatedExpr(test.i); expr != test.expr {
+			t.Errorf("generatedExpr(%d) = %q, want %q", test.i, expr, test.expr)
+		}
+	}
+	if !b.generatedExprEqual("(price * (qty + 1))", 1) {
+		t.Errorf("generatedExprEqual() is false for Postgres's rendering of the same expression")
+	}
+}
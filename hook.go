@@ -0,0 +1,102 @@
+package pgx
+
+import (
+	"reflect"
+)
+
+type (
+	// BeforeInserter is called by Insert and InsertAll before the row is written, returning error aborts the insert
+	BeforeInserter interface {
+		BeforeInsert() error
+	}
+	// AfterInserter is called by Insert and InsertAll after the row is written
+	AfterInserter interface {
+		AfterInsert() error
+	}
+	// AfterFinder is called by Find, FindWhere and QueryWhere on each row after it's scanned and its preloaded relations are loaded
+	AfterFinder interface {
+		AfterFind() error
+	}
+	// BeforeUpdater is called by Update before the row is written, returning error aborts the update
+	BeforeUpdater interface {
+		BeforeUpdate() error
+	}
+	// BeforeDeleter is called by Delete, DeleteWhere and their hard variants on each row to be deleted, returning error aborts the delete
+	BeforeDeleter interface {
+		BeforeDelete() error
+	}
+)
+
+// beforeInsert calls BeforeInsert on value, value must be addressable
+func beforeInsert(value reflect.Value) error {
+	if h, ok := value.Addr().Interface().(BeforeInserter); ok {
+		return h.BeforeInsert()
+	}
+	return nil
+}
+
+// afterInsert calls AfterInsert on value, value must be addressable
+func afterInsert(value reflect.Value) error {
+	if h, ok := value.Addr().Interface().(AfterInserter); ok {
+		return h.AfterInsert()
+	}
+	return nil
+}
+
+// afterFind calls AfterFind on v, v must be a pointer
+func afterFind(v reflect.Value) error {
+	if h, ok := v.Interface().(AfterFinder); ok {
+		return h.AfterFind()
+	}
+	return nil
+}
+
+// beforeUpdate calls BeforeUpdate on value, value must be addressable
+func beforeUpdate(value reflect.Value) error {
+	if h, ok := value.Addr().Interface().(BeforeUpdater); ok {
+		return h.BeforeUpdate()
+	}
+	return nil
+}
+
+// withoutQueryOptions returns a copy of b loading whole rows, without the receiver's Select, Omit and Preload
+func (b *BaseModel) withoutQueryOptions() *BaseModel {
+	c := *b
+	c.selects, c.omits, c.preloads = nil, nil, nil
+	return &c
+}
+
+func (b *BaseModel) hasBeforeDelete() bool {
+	return reflect.PtrTo(b.Type).Implements(reflect.TypeOf((*BeforeDeleter)(nil)).Elem())
+}
+
+// beforeDelete loads the row to be deleted and calls BeforeDelete on it
+func (b *BaseModel) beforeDelete(ids []interface{}) error {
+	if !b.hasBeforeDelete() {
+		return nil
+	}
+	v, e := b.withoutQueryOptions().Find(ids...)
+	if e != nil {
+		return e
+	}
+	return v.(BeforeDeleter).BeforeDelete()
+}
+
+// beforeDeleteWhere loads rows that match 'where' condition and calls BeforeDelete on each of them
+func (b *BaseModel) beforeDeleteWhere(where string, args []interface{}) error {
+	if !b.hasBeforeDelete() {
+		return nil
+	}
+	vs, e := b.withoutQueryOptions().QueryWhere(where, args...)
+	if e != nil {
+		return e
+	}
+	sliceValue := reflect.ValueOf(vs)
+	for i := 0; i < sliceValue.Len(); i++ {
+		e = sliceValue.Index(i).Interface().(BeforeDeleter).BeforeDelete()
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
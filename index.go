@@ -1,10 +1,10 @@
 package pgx
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
-	"log"
-	"net/url"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -13,11 +13,16 @@ import (
 
 type (
 	indexModel struct {
-		unique bool
-		keys   []indexKey
+		unique       bool
+		keys         []indexKey
+		method       string
+		where        string
+		include      []string
+		concurrently bool
 	}
 	indexKey struct {
 		lower    bool
+		expr     string
 		key      string
 		sequence string
 	}
@@ -26,6 +31,7 @@ type (
 		TableName  string `db:"tablename"`
 		IndexName  string `db:"indexname"`
 		IndexDef   string `db:"indexdef"`
+		IsPrimary  bool   `db:"indisprimary"`
 	}
 )
 
@@ -39,71 +45,169 @@ func (i *indexModel) ToIndexName(tableName string) string {
 	buf := new(strings.Builder)
 	buf.WriteString(tableName + "_")
 	for _, k := range i.keys {
-		if k.lower {
-			buf.WriteString("lower_")
-			continue
+		switch {
+		case k.expr != "":
+			buf.WriteString("expr_" + k.key + "_")
+		case k.lower:
+			buf.WriteString("lower_" + k.key + "_")
+		default:
+			buf.WriteString(k.key + "_")
+		}
+		if k.sequence == "desc" {
+			buf.WriteString("desc_")
 		}
-		buf.WriteString(k.key + "_")
+	}
+	if i.method != "" && i.method != "btree" {
+		buf.WriteString(i.method + "_")
+	}
+	if len(i.include) > 0 {
+		buf.WriteString("include_")
+	}
+	if i.where != "" {
+		buf.WriteString("partial_")
 	}
 	buf.WriteString("idx")
 	return buf.String()
 }
 
+// getMethod returns index method, btree by default
+func (i *indexModel) getMethod() string {
+	if i.method == "" {
+		return "btree"
+	}
+	return i.method
+}
+
+// merge copies index-wide options of another field in the same group
+func (i *indexModel) merge(other indexModel) {
+	if other.method != "" {
+		i.method = other.method
+	}
+	if other.where != "" {
+		i.where = other.where
+	}
+	i.include = append(i.include, other.include...)
+	i.concurrently = i.concurrently || other.concurrently
+}
+
+func (k *indexKey) toKeyDef() string {
+	switch {
+	case k.expr != "":
+		return "(" + k.expr + ")"
+	case k.lower:
+		return "lower(" + k.key + ")"
+	}
+	return k.key
+}
+
+// splitIndexTag splits index tag into key value pairs by commas outside parentheses and quotes, so where= and expr= values may contain commas.
+// A value without key following include= is another include column, like "include=name,email"
+func splitIndexTag(index string) ([][2]string, error) {
+	items, e := splitTopLevel(index)
+	if e != nil {
+		return nil, e
+	}
+
+	pairs := [][2]string{}
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if !strings.Contains(item, "=") {
+			if len(pairs) > 0 && pairs[len(pairs)-1][0] == "include" && item != "" {
+				pairs = append(pairs, [2]string{"include", item})
+				continue
+			}
+			return nil, errors.New("option without value:" + item)
+		}
+		k := strings.TrimSpace(item[:strings.Index(item, "=")])
+		v := strings.TrimSpace(item[strings.Index(item, "=")+1:])
+		pairs = append(pairs, [2]string{k, v})
+	}
+	return pairs, nil
+}
+
+// toIndexModels parses index tags with format like: map[column_name]"single=asc,unique=true,lower=true,group=unique,desc=true,method=gin,where=status in ('a','b'),include=name,email,concurrently=true".
+// Values are taken literally, expr= and where= may contain commas inside parentheses or quotes
 func toIndexModels(indexes map[string]string) ([]indexModel, error) {
 	imodels := []indexModel{}
 	groupMap := make(map[string]indexModel)
 	for key, index := range indexes {
-		vs, e := url.ParseQuery(strings.ReplaceAll(index, ",", "&"))
+		pairs, e := splitIndexTag(index)
 		if e != nil {
-			return nil, errors.New("field '" + key + "', invalid index tag format:" + index)
+			return nil, errors.New("field '" + key + "', invalid index tag format:" + index + ", " + e.Error())
 		}
 
 		imodel := indexModel{}
+		single := false
+		singleSequence := "asc"
+		sequence := "asc"
 		lower := false
+		expr := ""
 		group := ""
-		for k := range vs {
-			v := vs.Get(k)
+		for _, pair := range pairs {
+			k, v := pair[0], pair[1]
 			switch k {
 			case "single":
-				if len(imodel.keys) > 0 {
+				if single {
 					return nil, errors.New("field '" + key + "': duplicated key 'single'")
 				}
-				indexKey := indexKey{
-					key:      key,
-					sequence: "asc",
-				}
+				single = true
 				if v == "desc" {
-					indexKey.sequence = "desc"
+					singleSequence = "desc"
 				}
-				imodel.keys = append(imodel.keys, indexKey)
 			case "unique", "uniq":
-				imodel.unique = vs.Get(k) == "true"
+				imodel.unique = v == "true"
 			case "group":
-				group = vs.Get(k)
+				group = v
 			case "lower":
 				lower = v == "true"
+			case "desc":
+				if v == "true" {
+					sequence = "desc"
+				}
+			case "expr":
+				expr = v
+			case "method":
+				switch v {
+				case "btree", "hash", "gist", "spgist", "gin", "brin":
+				default:
+					return nil, errors.New("field '" + key + "', unsupported index method:" + v)
+				}
+				imodel.method = v
+			case "where":
+				imodel.where = v
+			case "include":
+				imodel.include = append(imodel.include, v)
+			case "concurrently":
+				imodel.concurrently = v == "true"
 			default:
 				return nil, errors.New("field '" + key + "', unsupported key:" + k)
 			}
 		}
-
 		// normal index
 		if group == "" {
-			if len(imodel.keys) == 0 {
-				imodel.keys = append(imodel.keys, indexKey{
-					key:      key,
-					sequence: "asc",
-					lower:    lower,
-				})
+			if single && sequence == "asc" {
+				sequence = singleSequence
 			}
+			imodel.keys = append(imodel.keys, indexKey{
+				key:      key,
+				sequence: sequence,
+				lower:    lower,
+				expr:     expr,
+			})
 			imodels = append(imodels, imodel)
 			continue
 		}
 
 		//another single index
-		if len(imodel.keys) > 0 {
-			imodel.keys[0].lower = lower
-			imodels = append(imodels, imodel)
+		if single {
+			singleModel := imodel
+			singleModel.keys = []indexKey{{
+				key:      key,
+				sequence: singleSequence,
+				lower:    lower,
+				expr:     expr,
+			}}
+			imodels = append(imodels, singleModel)
 		}
 
 		//group index
@@ -111,12 +215,14 @@ func toIndexModels(indexes map[string]string) ([]indexModel, error) {
 		if !ok {
 			before.keys = append(before.keys, indexKey{
 				key:      strToolkit.SubBefore(key, ",", key),
-				sequence: "asc",
+				sequence: sequence,
 				lower:    lower,
+				expr:     expr,
 			})
 			if strings.HasPrefix(group, "unique") {
 				before.unique = true
 			}
+			before.merge(imodel)
 			groupMap[group] = before
 			continue
 		}
@@ -124,9 +230,11 @@ func toIndexModels(indexes map[string]string) ([]indexModel, error) {
 		//append
 		before.keys = append(before.keys, indexKey{
 			key:      key,
-			sequence: "asc",
+			sequence: sequence,
 			lower:    lower,
+			expr:     expr,
 		})
+		before.merge(imodel)
 		groupMap[group] = before
 	}
 
@@ -138,7 +246,7 @@ func toIndexModels(indexes map[string]string) ([]indexModel, error) {
 	return imodels, nil
 }
 
-// createIndexFromField create index with format like: map[column_name]"single=asc,unique=true,lower=true,group=unique"
+// createIndexFromField creates indexes parsed by toIndexModels
 func (b *BaseModel) createIndexFromField(imodels []indexModel) error {
 	if len(imodels) == 0 {
 		return nil
@@ -147,51 +255,215 @@ func (b *BaseModel) createIndexFromField(imodels []indexModel) error {
 	for _, imodel := range imodels {
 		e := b.createIndex(imodel)
 		if e != nil {
-			log.Println(e)
 			return e
 		}
 	}
 	return nil
 }
 
-func (b *BaseModel) createIndex(imodel indexModel) error {
+func (b *BaseModel) getCreateIndexSQL(imodel indexModel, name string) string {
 	builder := new(strings.Builder)
 	builder.WriteString("create ")
 	if imodel.unique {
 		builder.WriteString("unique ")
 	}
-	builder.WriteString("index on " + b.Schema + "." + b.TableName + " (")
+	builder.WriteString("index ")
+	if imodel.concurrently {
+		builder.WriteString("concurrently ")
+	}
+	builder.WriteString(name + " on " + b.Schema + "." + b.TableName + " using " + imodel.getMethod() + " (")
 	for i, key := range imodel.keys {
-		if key.lower {
-			builder.WriteString("lower(" + key.key + ")")
-		} else {
-			builder.WriteString(key.key)
+		builder.WriteString(key.toKeyDef())
+		//only btree supports ordering
+		if imodel.getMethod() == "btree" {
+			builder.WriteString(" " + key.sequence)
 		}
-		builder.WriteString(" asc")
 		if i < len(imodel.keys)-1 {
 			builder.WriteString(",")
 		}
 	}
 	builder.WriteString(")")
-	query := builder.String()
-	_, e := b.Pool.Exec(query)
+	if len(imodel.include) > 0 {
+		builder.WriteString(" include (" + strings.Join(imodel.include, ",") + ")")
+	}
+	if imodel.where != "" {
+		builder.WriteString(" where " + imodel.where)
+	}
+	return builder.String()
+}
+
+func (b *BaseModel) createIndex(imodel indexModel) error {
+	query := b.getCreateIndexSQL(imodel, imodel.ToIndexName(b.TableName))
+	_, e := b.exec(query)
 	if e != nil {
 		return fmt.Errorf("%w:%s", e, query)
 	}
 	return nil
 }
 
-func (b *BaseModel) dropIndex(name string) error {
-	query := `drop index ` + name
-	_, e := b.Pool.Exec(query)
+// recreateIndex replaces the remote index having the same name with imodel, concurrent indexes are built under a temporary name and then swapped in
+func (b *BaseModel) recreateIndex(imodel indexModel) error {
+	name := imodel.ToIndexName(b.TableName)
+	if !imodel.concurrently {
+		e := b.dropIndex(name, false)
+		if e != nil {
+			return e
+		}
+		return b.createIndex(imodel)
+	}
+
+	tmpName := name + "_new"
+	e := b.dropIndex(tmpName, true)
+	if e != nil {
+		return e
+	}
+	query := b.getCreateIndexSQL(imodel, tmpName)
+	_, e = b.exec(query)
+	if e != nil {
+		return fmt.Errorf("%w:%s", e, query)
+	}
+	e = b.dropIndex(name, true)
+	if e != nil {
+		return e
+	}
+	return b.renameIndex(tmpName, name)
+}
+
+func (b *BaseModel) renameIndex(name, newName string) error {
+	query := `alter index ` + b.Schema + `.` + name + ` rename to ` + newName
+	_, e := b.exec(query)
 	if e != nil {
 		return fmt.Errorf("%w:%s", e, query)
 	}
 	return nil
 }
 
+// findRenamedIndex returns a remote index not claimed by any local index or constraint, having the same definition as imodel under another name,
+// like indexes named by older versions of ToIndexName
+func (b *BaseModel) findRenamedIndex(imodel indexModel, remotes []IndexSchema, claimed map[string]bool) (IndexSchema, bool) {
+	candidates := []IndexSchema{}
+	for _, remote := range remotes {
+		if remote.IsPrimary || claimed[remote.IndexName] {
+			continue
+		}
+		if normalizeIndexDef(remote.IndexDef) == normalizeIndexDef(b.getCreateIndexSQL(imodel, remote.IndexName)) {
+			return remote, true
+		}
+		candidates = append(candidates, remote)
+	}
+	if len(candidates) == 0 {
+		return IndexSchema{}, false
+	}
+	def, e := b.renderIndexDef(imodel)
+	if e != nil {
+		return IndexSchema{}, false
+	}
+	for _, remote := range candidates {
+		if indexDefBody(remote.IndexDef) == indexDefBody(def) {
+			return remote, true
+		}
+	}
+	return IndexSchema{}, false
+}
+
+func (b *BaseModel) dropIndex(name string, concurrently bool) error {
+	query := `drop index `
+	if concurrently {
+		query += `concurrently if exists `
+	}
+	query += b.Schema + `.` + name
+	_, e := b.exec(query)
+	if e != nil {
+		return fmt.Errorf("%w:%s", e, query)
+	}
+	return nil
+}
+
+var (
+	indexDefCastRegexp  = regexp.MustCompile(`::[a-z_]+( varying| precision| with(out)? time zone)?(\[\])?`)
+	indexDefAscRegexp   = regexp.MustCompile(`\sasc( nulls last)?\b`)
+	indexDefSpaceRegexp = regexp.MustCompile(`[\s()"]+`)
+)
+
+// normalizeIndexDef reduces both pg_indexes.indexdef and getCreateIndexSQL output to a comparable form, dropping case, casts, default asc ordering, concurrently, parentheses and whitespace
+func normalizeIndexDef(def string) string {
+	def = strings.ToLower(def)
+	def = indexDefCastRegexp.ReplaceAllString(def, "")
+	def = strings.ReplaceAll(def, " concurrently ", " ")
+	def = indexDefAscRegexp.ReplaceAllString(def, "")
+	return indexDefSpaceRegexp.ReplaceAllString(def, "")
+}
+
+// indexDefBody returns def from its method on, prefixed with unique, leaving out index and table names
+func indexDefBody(def string) string {
+	def = strings.ToLower(def)
+	body := normalizeIndexDef(strToolkit.SubAfter(def, " using ", def))
+	if strings.HasPrefix(def, "create unique ") {
+		return "unique " + body
+	}
+	return body
+}
+
+// renderIndexDef returns Postgres's own rendering of imodel, built on an empty temporary copy of the table in a rolled back transaction
+func (b *BaseModel) renderIndexDef(imodel indexModel) (string, error) {
+	tx, e := b.Pool.BeginTx(b.context(), nil)
+	if e != nil {
+		return "", e
+	}
+	defer tx.Rollback()
+
+	tmp := *b
+	tmp.Schema = "pg_temp"
+	tmp.TableName = "pgx_index_def"
+	imodel.concurrently = false
+	name := imodel.ToIndexName(b.TableName)
+	for _, query := range []string{
+		`create temporary table ` + tmp.TableName + ` (like ` + b.Schema + `.` + b.TableName + `) on commit drop`,
+		tmp.getCreateIndexSQL(imodel, name),
+	} {
+		e = b.run(query, nil, func() (int64, error) {
+			_, e := tx.ExecContext(b.context(), query)
+			return 0, e
+		})
+		if e != nil {
+			return "", fmt.Errorf("%w:%s", e, query)
+		}
+	}
+
+	var def string
+	query := `select pg_get_indexdef(to_regclass('pg_temp.'||$1))`
+	e = b.queryRowTx(tx, query, []interface{}{name}, &def)
+	if e != nil {
+		return "", fmt.Errorf("%w:%s", e, query)
+	}
+	return def, nil
+}
+
+// indexDefEqual compares remoteDef with imodel. Definitions differing in text are rendered by Postgres before comparing again,
+// since it rewrites parts like predicates (!= to <>, in to = any). Indexes that can't be rendered are reported equal, so they're never dropped by mistake
+func (b *BaseModel) indexDefEqual(remoteDef string, imodel indexModel) bool {
+	name := imodel.ToIndexName(b.TableName)
+	if normalizeIndexDef(remoteDef) == normalizeIndexDef(b.getCreateIndexSQL(imodel, name)) {
+		return true
+	}
+	def, e := b.renderIndexDef(imodel)
+	if e != nil {
+		b.log(LevelWarn, "index definition check skipped", Field{Key: "index", Value: name}, Field{Key: "error", Value: e})
+		return true
+	}
+	return indexDefBody(remoteDef) == indexDefBody(def)
+}
+
 func (b *BaseModel) GetIndexes() ([]IndexSchema, error) {
-	rows, e := b.Pool.Query(`select schemaname,tablename,indexname,indexdef from pg_indexes where tablename=$1`, b.TableName)
+	return descIndexes(b.query, b.Schema, b.TableName)
+}
+
+func DescIndexes(pool *sql.DB, schema, tableName string) ([]IndexSchema, error) {
+	return descIndexes(pool.Query, schema, tableName)
+}
+
+func descIndexes(query queryFunc, schema, tableName string) ([]IndexSchema, error) {
+	rows, e := query(`select i.schemaname,i.tablename,i.indexname,i.indexdef,x.indisprimary from pg_indexes i join pg_namespace n on n.nspname=i.schemaname join pg_class c on c.relname=i.indexname and c.relnamespace=n.oid join pg_index x on x.indexrelid=c.oid where i.schemaname=$1 and i.tablename=$2`, schema, tableName)
 	if e != nil {
 		return nil, e
 	}
@@ -199,7 +471,7 @@ func (b *BaseModel) GetIndexes() ([]IndexSchema, error) {
 	vs := []IndexSchema{}
 	for rows.Next() {
 		v := IndexSchema{}
-		e = rows.Scan(&v.SchemaName, &v.TableName, &v.IndexName, &v.IndexDef)
+		e = rows.Scan(&v.SchemaName, &v.TableName, &v.IndexName, &v.IndexDef, &v.IsPrimary)
 		if e != nil {
 			break
 		}
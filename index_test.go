@@ -0,0 +1,206 @@
+package pgx
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestNormalizeIndexDef(t *testing.T) {
+	tests := []struct {
+		local  string
+		remote string
+		equal  bool
+	}{
+		{
+			local:  "create index users_name_idx on public.users using btree (name asc)",
+			remote: "CREATE INDEX users_name_idx ON public.users USING btree (name)",
+			equal:  true,
+		},
+		{
+			local:  "create unique index concurrently users_lower_email_idx on public.users using btree (lower(email) asc)",
+			remote: "CREATE UNIQUE INDEX users_lower_email_idx ON public.users USING btree (lower((email)::text))",
+			equal:  true,
+		},
+		{
+			local:  "create index users_created_at_desc_idx on public.users using btree (created_at desc)",
+			remote: "CREATE INDEX users_created_at_desc_idx ON public.users USING btree (created_at DESC)",
+			equal:  true,
+		},
+		{
+			local:  "create index users_name_partial_idx on public.users using btree (name asc) where deleted_at is null",
+			remote: "CREATE INDEX users_name_partial_idx ON public.users USING btree (name) WHERE (deleted_at IS NULL)",
+			equal:  true,
+		},
+		{
+			local:  "create index users_name_idx on public.users using btree (name asc)",
+			remote: "CREATE INDEX users_name_idx ON public.users USING btree (name DESC)",
+			equal:  false,
+		},
+		{
+			local:  "create index users_name_idx on public.users using btree (name asc)",
+			remote: "CREATE UNIQUE INDEX users_name_idx ON public.users USING btree (name)",
+			equal:  false,
+		},
+		{
+			local:  "create index users_ascii_idx on public.users using btree (ascii asc)",
+			remote: "CREATE INDEX users_ascii_idx ON public.users USING btree (ascii)",
+			equal:  true,
+		},
+	}
+	for _, test := range tests {
+		if got := normalizeIndexDef(test.local) == normalizeIndexDef(test.remote); got != test.equal {
+			t.Errorf("normalizeIndexDef(%q) == normalizeIndexDef(%q) is %v, want %v", test.local, test.remote, got, test.equal)
+		}
+	}
+}
+
+func TestIndexDefBody(t *testing.T) {
+	tests := []struct {
+		remote   string
+		rendered string
+		equal    bool
+	}{
+		{
+			remote:   "CREATE INDEX users_status_partial_idx ON public.users USING btree (status) WHERE (status <> 'a'::text)",
+			rendered: "CREATE INDEX users_status_partial_idx ON pg_temp_3.pgx_index_def USING btree (status) WHERE (status <> 'a'::text)",
+			equal:    true,
+		},
+		{
+			remote:   "CREATE UNIQUE INDEX users_email_idx ON public.users USING btree (email)",
+			rendered: "CREATE INDEX users_email_idx ON pg_temp_3.pgx_index_def USING btree (email)",
+			equal:    false,
+		},
+	}
+	for _, test := range tests {
+		if got := indexDefBody(test.remote) == indexDefBody(test.rendered); got != test.equal {
+			t.Errorf("indexDefBody(%q) == indexDefBody(%q) is %v, want %v", test.remote, test.rendered, got, test.equal)
+		}
+	}
+}
+
+func TestSplitIndexTag(t *testing.T) {
+	tests := []struct {
+		tag   string
+		pairs [][2]string
+		err   bool
+	}{
+		{tag: "unique=true", pairs: [][2]string{{"unique", "true"}}},
+		{tag: "include=b,c", pairs: [][2]string{{"include", "b"}, {"include", "c"}}},
+		{tag: "where=status in ('a','b'),desc=true", pairs: [][2]string{{"where", "status in ('a','b')"}, {"desc", "true"}}},
+		{tag: "expr=coalesce(a,b)", pairs: [][2]string{{"expr", "coalesce(a,b)"}}},
+		{tag: "where=a+b%2=1", pairs: [][2]string{{"where", "a+b%2=1"}}},
+		{tag: "where=name<>'a,b'", pairs: [][2]string{{"where", "name<>'a,b'"}}},
+		{tag: "unique", err: true},
+		{tag: "expr=lower(a", err: true},
+	}
+	for _, test := range tests {
+		pairs, e := splitIndexTag(test.tag)
+		if (e != nil) != test.err {
+			t.Errorf("splitIndexTag(%q) error = %v, want error %v", test.tag, e, test.err)
+			continue
+		}
+		if !test.err && !reflect.DeepEqual(pairs, test.pairs) {
+			t.Errorf("splitIndexTag(%q) = %v, want %v", test.tag, pairs, test.pairs)
+		}
+	}
+}
+
+func TestToIndexModels(t *testing.T) {
+	b := &BaseModel{Schema: "public", TableName: "users"}
+	tests := []struct {
+		indexes map[string]string
+		sqls    []string
+		err     bool
+	}{
+		{
+			indexes: map[string]string{"name": "single=asc"},
+			sqls:    []string{"create index users_name_idx on public.users using btree (name asc)"},
+		},
+		{
+			indexes: map[string]string{"created_at": "single=desc"},
+			sqls:    []string{"create index users_created_at_desc_idx on public.users using btree (created_at desc)"},
+		},
+		{
+			indexes: map[string]string{"email": "unique=true,lower=true"},
+			sqls:    []string{"create unique index users_lower_email_idx on public.users using btree (lower(email) asc)"},
+		},
+		{
+			indexes: map[string]string{"email": "expr=lower(email)", "name": "expr=lower(name)"},
+			sqls: []string{
+				"create index users_expr_email_idx on public.users using btree ((lower(email)) asc)",
+				"create index users_expr_name_idx on public.users using btree ((lower(name)) asc)",
+			},
+		},
+		{
+			indexes: map[string]string{"a": "group=g,desc=true", "b": "group=g"},
+			sqls:    []string{"create index users_a_desc_b_idx on public.users using btree (a desc,b asc)"},
+		},
+		{
+			indexes: map[string]string{"a": "group=unique_g,single=desc", "b": "group=unique_g"},
+			sqls: []string{
+				"create index users_a_desc_idx on public.users using btree (a desc)",
+				"create unique index users_a_b_idx on public.users using btree (a asc,b asc)",
+			},
+		},
+		{
+			indexes: map[string]string{"tags": "method=gin"},
+			sqls:    []string{"create index users_tags_gin_idx on public.users using gin (tags)"},
+		},
+		{
+			indexes: map[string]string{"a": "include=b,c,where=status in ('a','b'),concurrently=true"},
+			sqls:    []string{"create index concurrently users_a_include_partial_idx on public.users using btree (a asc) include (b,c) where status in ('a','b')"},
+		},
+		{
+			indexes: map[string]string{"a": "method=foo"},
+			err:     true,
+		},
+		{
+			indexes: map[string]string{"a": "foo=bar"},
+			err:     true,
+		},
+		{
+			indexes: map[string]string{"a": "single=asc,single=desc"},
+			err:     true,
+		},
+	}
+	for _, test := range tests {
+		imodels, e := toIndexModels(test.indexes)
+		if (e != nil) != test.err {
+			t.Errorf("toIndexModels(%v) error = %v, want error %v", test.indexes, e, test.err)
+			continue
+		}
+		if test.err {
+			continue
+		}
+		sqls := []string{}
+		for _, imodel := range imodels {
+			sqls = append(sqls, b.getCreateIndexSQL(imodel, imodel.ToIndexName(b.TableName)))
+		}
+		sort.Strings(sqls)
+		if !reflect.DeepEqual(sqls, test.sqls) {
+			t.Errorf("toIndexModels(%v) creates %q, want %q", test.indexes, sqls, test.sqls)
+		}
+	}
+}
+
+func TestFindRenamedIndex(t *testing.T) {
+	b := &BaseModel{Schema: "public", TableName: "users"}
+	imodels, e := toIndexModels(map[string]string{"email": "unique=true,lower=true"})
+	if e != nil {
+		t.Fatal(e)
+	}
+	remotes := []IndexSchema{
+		{IndexName: "users_pkey", IndexDef: "CREATE UNIQUE INDEX users_pkey ON public.users USING btree (id)", IsPrimary: true},
+		{IndexName: "users_name_idx", IndexDef: "CREATE INDEX users_name_idx ON public.users USING btree (name)"},
+		{IndexName: "users_lower_idx", IndexDef: "CREATE UNIQUE INDEX users_lower_idx ON public.users USING btree (lower((email)::text))"},
+	}
+
+	remote, ok := b.findRenamedIndex(imodels[0], remotes, map[string]bool{"users_name_idx": true})
+	if !ok || remote.IndexName != "users_lower_idx" {
+		t.Errorf("findRenamedIndex() = %v %v, want users_lower_idx", remote.IndexName, ok)
+	}
+	if _, ok := b.findRenamedIndex(imodels[0], remotes, map[string]bool{"users_name_idx": true, "users_lower_idx": true}); ok {
+		t.Errorf("findRenamedIndex() finds a claimed index")
+	}
+}
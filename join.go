@@ -0,0 +1,207 @@
+package pgx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/StevenZack/tools/strToolkit"
+)
+
+// Join describes another model joined to a BaseModel. LeftKey is a column of the base model and RightKey a column of Model,
+// when both are empty they're inferred from `fk` tags or relations declared between the two models
+type Join struct {
+	Model    *BaseModel
+	Kind     string // inner (default) or left
+	LeftKey  string
+	RightKey string
+}
+
+// InnerJoin joins other on keys declared between the two models
+func InnerJoin(other *BaseModel) Join {
+	return Join{Model: other, Kind: "inner"}
+}
+
+// LeftJoin left joins other on keys declared between the two models
+func LeftJoin(other *BaseModel) Join {
+	return Join{Model: other, Kind: "left"}
+}
+
+// On returns a copy of j joining on b.leftKey = j.Model.rightKey
+func (j Join) On(leftKey, rightKey string) Join {
+	j.LeftKey = leftKey
+	j.RightKey = rightKey
+	return j
+}
+
+// toJoinKeys returns the join keys, inferring them from fk tags and relations if not set
+func (b *BaseModel) toJoinKeys(j Join) (string, string, error) {
+	if j.LeftKey != "" && j.RightKey != "" {
+		if b.columnIndex(j.LeftKey) == -1 {
+			return "", "", errors.New("table " + b.TableName + " has no column " + j.LeftKey)
+		}
+		if j.Model.columnIndex(j.RightKey) == -1 {
+			return "", "", errors.New("table " + j.Model.TableName + " has no column " + j.RightKey)
+		}
+		return j.LeftKey, j.RightKey, nil
+	}
+
+	other := j.Model
+	for _, fk := range b.fks {
+		if strToolkit.SubAfterLast(fk.refTable, ".", fk.refTable) == other.TableName {
+			return fk.column, fk.refColumn, nil
+		}
+	}
+	for _, fk := range other.fks {
+		if strToolkit.SubAfterLast(fk.refTable, ".", fk.refTable) == b.TableName {
+			return fk.refColumn, fk.column, nil
+		}
+	}
+	for _, rel := range b.relations {
+		if rel.elem != other.Type {
+			continue
+		}
+		switch rel.kind {
+		case "belongs_to":
+			c, e := other.refColumn(rel.ref)
+			if e != nil {
+				return "", "", e
+			}
+			return rel.fk, other.dbTags[c], nil
+		case "has_one", "has_many":
+			c, e := b.refColumn(rel.ref)
+			if e != nil {
+				return "", "", e
+			}
+			return b.dbTags[c], rel.fk, nil
+		}
+	}
+	return "", "", errors.New("no join keys declared between " + b.TableName + " and " + other.TableName)
+}
+
+// resolveJoinColumn resolves a result struct `db` tag, either "table.column" or an unambiguous "column", to a qualified column
+func (b *BaseModel) resolveJoinColumn(other *BaseModel, dbTag string) (string, error) {
+	if strings.Contains(dbTag, ".") {
+		table := dbTag[:strings.Index(dbTag, ".")]
+		column := dbTag[strings.Index(dbTag, ".")+1:]
+		for _, m := range []*BaseModel{b, other} {
+			if m.TableName == table && m.columnIndex(column) > -1 {
+				return dbTag, nil
+			}
+		}
+		return "", errors.New("column " + dbTag + " is not found in joined tables")
+	}
+
+	found := []string{}
+	for _, m := range []*BaseModel{b, other} {
+		if m.columnIndex(dbTag) > -1 {
+			found = append(found, m.TableName+"."+dbTag)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", errors.New("column " + dbTag + " is not found in joined tables")
+	case 1:
+		return found[0], nil
+	}
+	return "", errors.New("column " + dbTag + " is ambiguous, prefix it with table name: " + strings.Join(found, " or "))
+}
+
+// GetJoinSQL returns field indexes of result type t, and select SQL of b joined with j
+func (b *BaseModel) GetJoinSQL(t reflect.Type, j Join) ([]int, string, error) {
+	leftKey, rightKey, e := b.toJoinKeys(j)
+	if e != nil {
+		return nil, "", e
+	}
+	kind := "inner"
+	switch j.Kind {
+	case "", "inner":
+	case "left":
+		kind = "left"
+	default:
+		return nil, "", errors.New("unsupported join kind:" + j.Kind)
+	}
+
+	fieldIndexes := []int{}
+	columns := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		dbTag, ok := t.Field(i).Tag.Lookup("db")
+		if !ok {
+			continue
+		}
+		column, e := b.resolveJoinColumn(j.Model, dbTag)
+		if e != nil {
+			return nil, "", e
+		}
+		fieldIndexes = append(fieldIndexes, i)
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil, "", errors.New("result type " + t.String() + " has no `db` tagged field")
+	}
+
+	query := `select ` + strings.Join(columns, ",") + ` from ` + b.fromTable() + ` ` + kind + ` join ` + j.Model.fromTable() + ` on ` + b.TableName + `.` + leftKey + `=` + j.Model.TableName + `.` + rightKey
+	return fieldIndexes, query, nil
+}
+
+// JoinWhere queries b joined with j, that matches 'where' condition, scanning rows into dst (*[]struct or *[]*struct).
+// Result struct fields map by `db` tag, prefixed with table name like "users.id" when the column exists in both tables.
+// Use sql.Null* fields for columns of left joined tables
+func (b *BaseModel) JoinWhere(dst interface{}, j Join, where string, args ...interface{}) error {
+	dstValue := reflect.ValueOf(dst)
+	if dstValue.Kind() != reflect.Ptr || dstValue.Elem().Kind() != reflect.Slice {
+		return errors.New("dst must be pointer to slice:" + dstValue.Type().String())
+	}
+	sliceValue := dstValue.Elem()
+	t := sliceValue.Type().Elem()
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return errors.New("dst element must be struct type:" + t.String())
+	}
+
+	fieldIndexes, query, e := b.GetJoinSQL(t, j)
+	if e != nil {
+		return e
+	}
+	query = query + toWhere(where)
+
+	rows, e := b.query(query, args...)
+	if e != nil {
+		return toError(e, query)
+	}
+	vs := reflect.MakeSlice(sliceValue.Type(), 0, 2)
+	for rows.Next() {
+		v := reflect.New(t)
+		fieldArgs := []interface{}{}
+		for _, i := range fieldIndexes {
+			fieldArgs = append(fieldArgs, v.Elem().Field(i).Addr().Interface())
+		}
+		e = rows.Scan(fieldArgs...)
+		if e != nil {
+			break
+		}
+		if isPtr {
+			vs = reflect.Append(vs, v)
+		} else {
+			vs = reflect.Append(vs, v.Elem())
+		}
+	}
+
+	// check err
+	if closeErr := rows.Close(); closeErr != nil {
+		return fmt.Errorf("rows.Close() err:%w", closeErr)
+	}
+	if e != nil {
+		return e
+	}
+	if e = rows.Err(); e != nil {
+		return e
+	}
+
+	sliceValue.Set(vs)
+	return nil
+}
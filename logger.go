@@ -0,0 +1,88 @@
+package pgx
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	// LevelSilent disables all output when used as a StdLogger level
+	LevelSilent
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return "SILENT"
+}
+
+// Field is a structured key-value pair attached to a log entry, e.g. table, query, duration, rows
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger receives library log entries, implement it to forward logs to your own logging library
+type Logger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+// StdLogger writes entries at or above Level to a standard *log.Logger
+type StdLogger struct {
+	Logger *log.Logger
+	Level  Level
+}
+
+type discardLogger struct{}
+
+var (
+	// DefaultLogger is used by models created without WithLogger option
+	DefaultLogger Logger = NewStdLogger(log.New(os.Stderr, "", log.LstdFlags), LevelInfo)
+	// Discard silences library output entirely
+	Discard Logger = discardLogger{}
+)
+
+func NewStdLogger(logger *log.Logger, level Level) *StdLogger {
+	return &StdLogger{
+		Logger: logger,
+		Level:  level,
+	}
+}
+
+func (s *StdLogger) Log(level Level, msg string, fields ...Field) {
+	if level < s.Level || s.Level == LevelSilent {
+		return
+	}
+	builder := new(strings.Builder)
+	builder.WriteString("[" + level.String() + "] " + msg)
+	for _, f := range fields {
+		builder.WriteString(" " + f.Key + "=" + fmt.Sprint(f.Value))
+	}
+	s.Logger.Output(2, builder.String())
+}
+
+func (discardLogger) Log(level Level, msg string, fields ...Field) {}
+
+// log writes an entry to the model's logger, with the table field attached
+func (b *BaseModel) log(level Level, msg string, fields ...Field) {
+	if b.Logger == nil {
+		return
+	}
+	b.Logger.Log(level, msg, append([]Field{{Key: "table", Value: b.TableName}}, fields...)...)
+}
@@ -0,0 +1,320 @@
+package pgx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/lib/pq"
+)
+
+// staticPkField returns the primary key field of a model struct type, without creating its model
+func staticPkField(t reflect.Type) (reflect.StructField, error) {
+	pks := []reflect.StructField{}
+	first := -1
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, ok := field.Tag.Lookup("rel"); ok {
+			continue
+		}
+		if _, ok := field.Tag.Lookup("db"); !ok {
+			continue
+		}
+		if first == -1 {
+			first = i
+		}
+		if _, ok := field.Tag.Lookup("pk"); ok {
+			pks = append(pks, field)
+		}
+	}
+	switch len(pks) {
+	case 0:
+		//the first column is the primary key, same as NewBaseModel
+		if first == -1 {
+			return reflect.StructField{}, errors.New("no column found in " + t.String())
+		}
+		return t.Field(first), nil
+	case 1:
+		return pks[0], nil
+	}
+	return reflect.StructField{}, errors.New("many2many relation doesn't support composite primary keys of " + t.String())
+}
+
+// createJoinTables creates join tables of many2many relations if they don't exist, with foreign keys cascading deletes of either side.
+// Join tables whose related table doesn't exist yet are created along with the related model
+func (b *BaseModel) createJoinTables() error {
+	for _, rel := range b.relations {
+		if rel.kind != "many2many" {
+			continue
+		}
+		if len(b.pks) != 1 {
+			return errors.New("many2many relation doesn't support composite primary keys of " + b.Type.String())
+		}
+		ownerType, e := ToPostgreType(b.Type.Field(b.fieldIndexes[b.pks[0]]).Type, rel.fk, 0)
+		if e != nil {
+			return e
+		}
+		relatedPk, e := staticPkField(rel.elem)
+		if e != nil {
+			return e
+		}
+		relatedType, e := ToPostgreType(relatedPk.Type, rel.assocFk, 0)
+		if e != nil {
+			return e
+		}
+
+		relatedTable := b.Schema + `.` + ToTableName(rel.elem.Name())
+		var exists bool
+		query := `select to_regclass($1) is not null`
+		e = b.queryRow(query, []interface{}{relatedTable}, &exists)
+		if e != nil {
+			return fmt.Errorf("%w:%s", e, query)
+		}
+		if !exists {
+			b.log(LevelInfo, "join table to be created with related table", Field{Key: "table", Value: rel.through}, Field{Key: "related", Value: relatedTable})
+			continue
+		}
+
+		query = `create table if not exists ` + b.Schema + `.` + rel.through + ` (` + rel.fk + ` ` + ownerType + `,` + rel.assocFk + ` ` + relatedType + `,primary key (` + rel.fk + `,` + rel.assocFk + `))`
+		_, e = b.exec(query)
+		if e != nil {
+			return fmt.Errorf("%w:%s", e, query)
+		}
+
+		//foreign keys, not validated against links created before they existed
+		remoteFks, e := descForeignKeys(b.query, b.Database, b.Schema, rel.through)
+		if e != nil {
+			return e
+		}
+		for _, fk := range []foreignKey{
+			{column: rel.fk, refTable: b.Schema + `.` + b.TableName, refColumn: b.dbTags[b.pks[0]], onDelete: "CASCADE", onUpdate: "NO ACTION"},
+			{column: rel.assocFk, refTable: relatedTable, refColumn: relatedPk.Tag.Get("db"), onDelete: "CASCADE", onUpdate: "NO ACTION"},
+		} {
+			found := false
+			for _, remote := range remoteFks {
+				found = found || remote.ConstraintName == fk.ToConstraintName(rel.through)
+			}
+			if found {
+				continue
+			}
+			query = `alter table ` + b.Schema + `.` + rel.through + ` add ` + fk.ToConstraintDef(rel.through) + ` not valid`
+			_, e = b.exec(query)
+			if e != nil {
+				return fmt.Errorf("%w:%s", e, query)
+			}
+		}
+	}
+	return nil
+}
+
+// createPendingJoinTables creates join tables of other models' many2many relations to b, which were waiting for b's table
+func (b *BaseModel) createPendingJoinTables() error {
+	var e error
+	models.Range(func(key, value interface{}) bool {
+		other := value.(*BaseModel)
+		if other == b || other.Dsn != b.Dsn || other.Schema != b.Schema {
+			return true
+		}
+		for _, rel := range other.relations {
+			if rel.kind == "many2many" && rel.elem == b.Type {
+				e = other.createJoinTables()
+				return e == nil
+			}
+		}
+		return true
+	})
+	return e
+}
+
+func (b *BaseModel) many2manyOf(name string) (relation, *BaseModel, error) {
+	rel, e := b.relationOf(name)
+	if e != nil {
+		return rel, nil, e
+	}
+	if rel.kind != "many2many" {
+		return rel, nil, errors.New("field " + name + " is not a many2many relation")
+	}
+	related, e := b.modelOf(rel.elem)
+	if e != nil {
+		return rel, nil, e
+	}
+	return rel, related, nil
+}
+
+// toAssocKeys converts owner and related (key values or structs) to their primary keys
+func (b *BaseModel) toAssocKeys(related *BaseModel, owner interface{}, vs []interface{}) (interface{}, []interface{}, error) {
+	ownerKey, e := b.toPkArgs([]interface{}{owner})
+	if e != nil {
+		return nil, nil, e
+	}
+	keys := []interface{}{}
+	for _, v := range vs {
+		key, e := related.toPkArgs([]interface{}{v})
+		if e != nil {
+			return nil, nil, e
+		}
+		keys = append(keys, key[0])
+	}
+	return ownerKey[0], keys, nil
+}
+
+// Associate links owner (key value or struct) with related records (key values or structs) through the join table of many2many relation field name
+func (b *BaseModel) Associate(owner interface{}, name string, related ...interface{}) error {
+	rel, relatedModel, e := b.many2manyOf(name)
+	if e != nil {
+		return e
+	}
+	ownerKey, keys, e := b.toAssocKeys(relatedModel, owner, related)
+	if e != nil {
+		return e
+	}
+
+	query := `insert into ` + b.Schema + `.` + rel.through + ` (` + rel.fk + `,` + rel.assocFk + `) values ($1,$2) on conflict do nothing`
+	for _, key := range keys {
+		_, e = b.exec(query, ownerKey, key)
+		if e != nil {
+			return toError(e, query)
+		}
+	}
+	return nil
+}
+
+// Dissociate unlinks owner (key value or struct) from related records (key values or structs) of many2many relation field name
+func (b *BaseModel) Dissociate(owner interface{}, name string, related ...interface{}) (int64, error) {
+	rel, relatedModel, e := b.many2manyOf(name)
+	if e != nil {
+		return 0, e
+	}
+	ownerKey, keys, e := b.toAssocKeys(relatedModel, owner, related)
+	if e != nil {
+		return 0, e
+	}
+
+	query := `delete from ` + b.Schema + `.` + rel.through + ` where ` + rel.fk + `=$1 and ` + rel.assocFk + ` = any($2)`
+	result, e := b.exec(query, ownerKey, pq.Array(keys))
+	if e != nil {
+		return 0, toError(e, query)
+	}
+	return result.RowsAffected()
+}
+
+// ReplaceAssociations replaces all links of owner (key value or struct) in many2many relation field name with related records, in a transaction
+func (b *BaseModel) ReplaceAssociations(owner interface{}, name string, related ...interface{}) error {
+	rel, relatedModel, e := b.many2manyOf(name)
+	if e != nil {
+		return e
+	}
+	ownerKey, keys, e := b.toAssocKeys(relatedModel, owner, related)
+	if e != nil {
+		return e
+	}
+
+	tx, e := b.Pool.BeginTx(b.context(), nil)
+	if e != nil {
+		return e
+	}
+	query := `delete from ` + b.Schema + `.` + rel.through + ` where ` + rel.fk + `=$1`
+	e = b.run(query, []interface{}{ownerKey}, func() (int64, error) {
+		result, e := tx.ExecContext(b.context(), query, ownerKey)
+		if e != nil {
+			return -1, e
+		}
+		return result.RowsAffected()
+	})
+	if e != nil {
+		tx.Rollback()
+		return toError(e, query)
+	}
+
+	query = `insert into ` + b.Schema + `.` + rel.through + ` (` + rel.fk + `,` + rel.assocFk + `) values ($1,$2) on conflict do nothing`
+	for _, key := range keys {
+		e = b.run(query, []interface{}{ownerKey, key}, func() (int64, error) {
+			result, e := tx.ExecContext(b.context(), query, ownerKey, key)
+			if e != nil {
+				return -1, e
+			}
+			return result.RowsAffected()
+		})
+		if e != nil {
+			tx.Rollback()
+			return toError(e, query)
+		}
+	}
+	return tx.Commit()
+}
+
+// preloadMany2many loads related records of rel into vs ([]*struct), with one query on the join table and one on the related table
+func (b *BaseModel) preloadMany2many(vs reflect.Value, rel relation, related *BaseModel) error {
+	if len(b.pks) != 1 || len(related.pks) != 1 {
+		return errors.New("many2many relation doesn't support composite primary keys")
+	}
+	ownerKeyType := b.Type.Field(b.fieldIndexes[b.pks[0]]).Type
+	relatedKeyType := related.Type.Field(related.fieldIndexes[related.pks[0]]).Type
+
+	//owner keys
+	keys := reflect.MakeSlice(reflect.SliceOf(ownerKeyType), 0, vs.Len())
+	for i := 0; i < vs.Len(); i++ {
+		keys = reflect.Append(keys, b.columnField(vs.Index(i).Elem(), b.pks[0]))
+	}
+
+	//links
+	query := `select ` + rel.fk + `,` + rel.assocFk + ` from ` + b.Schema + `.` + rel.through + ` where ` + rel.fk + ` = any($1)`
+	rows, e := b.query(query, pq.Array(keys.Interface()))
+	if e != nil {
+		return toError(e, query)
+	}
+	links := make(map[string][]string)
+	relatedKeys := reflect.MakeSlice(reflect.SliceOf(relatedKeyType), 0, 2)
+	seen := make(map[string]bool)
+	for rows.Next() {
+		ownerKey := reflect.New(ownerKeyType)
+		relatedKey := reflect.New(relatedKeyType)
+		e = rows.Scan(ownerKey.Interface(), relatedKey.Interface())
+		if e != nil {
+			break
+		}
+		k := fmt.Sprint(relatedKey.Elem().Interface())
+		links[fmt.Sprint(ownerKey.Elem().Interface())] = append(links[fmt.Sprint(ownerKey.Elem().Interface())], k)
+		if !seen[k] {
+			seen[k] = true
+			relatedKeys = reflect.Append(relatedKeys, relatedKey.Elem())
+		}
+	}
+	//check err
+	if closeErr := rows.Close(); closeErr != nil {
+		return fmt.Errorf("rows.Close() err:%w", closeErr)
+	}
+	if e != nil {
+		return e
+	}
+	if e = rows.Err(); e != nil {
+		return e
+	}
+
+	//related records
+	children := make(map[string]reflect.Value)
+	if relatedKeys.Len() > 0 {
+		records, e := related.QueryWhere(related.dbTags[related.pks[0]]+" = any($1)", pq.Array(relatedKeys.Interface()))
+		if e != nil {
+			return e
+		}
+		recordValues := reflect.ValueOf(records)
+		for i := 0; i < recordValues.Len(); i++ {
+			record := recordValues.Index(i)
+			children[fmt.Sprint(related.columnField(record.Elem(), related.pks[0]).Interface())] = record
+		}
+	}
+
+	//assign
+	for i := 0; i < vs.Len(); i++ {
+		parent := vs.Index(i).Elem()
+		group := []reflect.Value{}
+		for _, k := range links[fmt.Sprint(b.columnField(parent, b.pks[0]).Interface())] {
+			if child, ok := children[k]; ok {
+				group = append(group, child)
+			}
+		}
+		assignRelation(parent.Field(rel.field), group)
+	}
+	return nil
+}
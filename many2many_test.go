@@ -0,0 +1,49 @@
+package pgx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStaticPkField(t *testing.T) {
+	type tag struct {
+		Id   uint32 `db:"id"`
+		Name string `db:"name"`
+	}
+	type relFirst struct {
+		Tags []tag  `rel:"many2many"`
+		Id   uint32 `db:"id"`
+	}
+	type customPk struct {
+		Name string `db:"name"`
+		Code string `db:"code" pk:""`
+	}
+	type compositePk struct {
+		A string `db:"a" pk:""`
+		B string `db:"b" pk:""`
+	}
+	type noColumn struct {
+		Tags []tag `rel:"many2many"`
+	}
+	tests := []struct {
+		t     reflect.Type
+		field string
+		err   bool
+	}{
+		{t: reflect.TypeOf(tag{}), field: "Id"},
+		{t: reflect.TypeOf(relFirst{}), field: "Id"},
+		{t: reflect.TypeOf(customPk{}), field: "Code"},
+		{t: reflect.TypeOf(compositePk{}), err: true},
+		{t: reflect.TypeOf(noColumn{}), err: true},
+	}
+	for _, test := range tests {
+		field, e := staticPkField(test.t)
+		if (e != nil) != test.err {
+			t.Errorf("staticPkField(%v) error = %v, want error %v", test.t, e, test.err)
+			continue
+		}
+		if !test.err && field.Name != test.field {
+			t.Errorf("staticPkField(%v) = %s, want %s", test.t, field.Name, test.field)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package pgx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// DefaultBuckets are the query latency histogram buckets in seconds
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+type (
+	// Metrics is a QueryHook counting queries, latencies and errors by table and operation, along with pool stats of registered models.
+	// It serves the Prometheus text exposition format as an http.Handler
+	Metrics struct {
+		buckets []float64
+
+		mu      sync.Mutex
+		queries map[queryLabels]*QueryMetric
+		errors  map[errorLabels]*ErrorMetric
+		pools   map[string]*sql.DB
+	}
+	queryLabels struct {
+		table     string
+		operation string
+	}
+	errorLabels struct {
+		table     string
+		operation string
+		sqlState  string
+	}
+	// QueryMetric is the count and latency histogram of queries of an operation on a table, Buckets are cumulative counts matching MetricsSnapshot.Buckets
+	QueryMetric struct {
+		Table     string
+		Operation string
+		Count     uint64
+		Sum       float64
+		Buckets   []uint64
+	}
+	// ErrorMetric is the count of failed queries of an operation on a table by SQLSTATE
+	ErrorMetric struct {
+		Table     string
+		Operation string
+		SQLState  string
+		Count     uint64
+	}
+	// MetricsSnapshot is a copy of collected metrics, sorted by labels
+	MetricsSnapshot struct {
+		Buckets []float64
+		Queries []QueryMetric
+		Errors  []ErrorMetric
+		Pools   map[string]sql.DBStats
+	}
+)
+
+// NewMetrics returns Metrics observing query latencies into buckets (in seconds), DefaultBuckets if none is given
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &Metrics{
+		buckets: buckets,
+		queries: make(map[queryLabels]*QueryMetric),
+		errors:  make(map[errorLabels]*ErrorMetric),
+		pools:   make(map[string]*sql.DB),
+	}
+}
+
+// AddPool registers pool whose sql.DB.Stats() are exported as gauges labeled by table
+func (m *Metrics) AddPool(table string, pool *sql.DB) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pools[table] = pool
+}
+
+func (m *Metrics) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	return ctx
+}
+
+func (m *Metrics) AfterQuery(ctx context.Context, event *QueryEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	labels := queryLabels{table: event.Table, operation: event.Operation()}
+	q, ok := m.queries[labels]
+	if !ok {
+		q = &QueryMetric{
+			Table:     labels.table,
+			Operation: labels.operation,
+			Buckets:   make([]uint64, len(m.buckets)),
+		}
+		m.queries[labels] = q
+	}
+	seconds := event.Duration.Seconds()
+	q.Count++
+	q.Sum += seconds
+	for i, bucket := range m.buckets {
+		if seconds <= bucket {
+			q.Buckets[i]++
+		}
+	}
+
+	if event.Err == nil || event.Err == sql.ErrNoRows {
+		return
+	}
+	sqlState := "unknown"
+	var pqErr *pq.Error
+	if errors.As(event.Err, &pqErr) {
+		sqlState = string(pqErr.Code)
+	}
+	errLabels := errorLabels{table: labels.table, operation: labels.operation, sqlState: sqlState}
+	em, ok := m.errors[errLabels]
+	if !ok {
+		em = &ErrorMetric{
+			Table:     errLabels.table,
+			Operation: errLabels.operation,
+			SQLState:  errLabels.sqlState,
+		}
+		m.errors[errLabels] = em
+	}
+	em.Count++
+}
+
+// Snapshot copies collected metrics and reads current pool stats
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := MetricsSnapshot{
+		Buckets: append([]float64{}, m.buckets...),
+		Pools:   make(map[string]sql.DBStats),
+	}
+	for _, q := range m.queries {
+		v := *q
+		v.Buckets = append([]uint64{}, q.Buckets...)
+		snapshot.Queries = append(snapshot.Queries, v)
+	}
+	for _, em := range m.errors {
+		snapshot.Errors = append(snapshot.Errors, *em)
+	}
+	for table, pool := range m.pools {
+		snapshot.Pools[table] = pool.Stats()
+	}
+	sort.Slice(snapshot.Queries, func(i, j int) bool {
+		a, b := snapshot.Queries[i], snapshot.Queries[j]
+		return a.Table+" "+a.Operation < b.Table+" "+b.Operation
+	})
+	sort.Slice(snapshot.Errors, func(i, j int) bool {
+		a, b := snapshot.Errors[i], snapshot.Errors[j]
+		return a.Table+" "+a.Operation+" "+a.SQLState < b.Table+" "+b.Operation+" "+b.SQLState
+	})
+	return snapshot
+}
+
+// WriteTo writes metrics in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	snapshot := m.Snapshot()
+	builder := new(strings.Builder)
+
+	writeHeader(builder, "pgx_queries_total", "counter", "Total number of queries executed.")
+	for _, q := range snapshot.Queries {
+		builder.WriteString("pgx_queries_total" + toLabels("table", q.Table, "operation", q.Operation) + " " + strconv.FormatUint(q.Count, 10) + "\n")
+	}
+
+	writeHeader(builder, "pgx_query_duration_seconds", "histogram", "Query latency in seconds.")
+	for _, q := range snapshot.Queries {
+		for i, bucket := range snapshot.Buckets {
+			builder.WriteString("pgx_query_duration_seconds_bucket" + toLabels("table", q.Table, "operation", q.Operation, "le", strconv.FormatFloat(bucket, 'g', -1, 64)) + " " + strconv.FormatUint(q.Buckets[i], 10) + "\n")
+		}
+		builder.WriteString("pgx_query_duration_seconds_bucket" + toLabels("table", q.Table, "operation", q.Operation, "le", "+Inf") + " " + strconv.FormatUint(q.Count, 10) + "\n")
+		builder.WriteString("pgx_query_duration_seconds_sum" + toLabels("table", q.Table, "operation", q.Operation) + " " + strconv.FormatFloat(q.Sum, 'g', -1, 64) + "\n")
+		builder.WriteString("pgx_query_duration_seconds_count" + toLabels("table", q.Table, "operation", q.Operation) + " " + strconv.FormatUint(q.Count, 10) + "\n")
+	}
+
+	writeHeader(builder, "pgx_query_errors_total", "counter", "Total number of failed queries by SQLSTATE.")
+	for _, em := range snapshot.Errors {
+		builder.WriteString("pgx_query_errors_total" + toLabels("table", em.Table, "operation", em.Operation, "sqlstate", em.SQLState) + " " + strconv.FormatUint(em.Count, 10) + "\n")
+	}
+
+	tables := []string{}
+	for table := range snapshot.Pools {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	poolMetrics := []struct {
+		name, typ, help string
+		value           func(s sql.DBStats) string
+	}{
+		{"pgx_pool_max_open_connections", "gauge", "Maximum number of open connections.", func(s sql.DBStats) string { return strconv.Itoa(s.MaxOpenConnections) }},
+		{"pgx_pool_open_connections", "gauge", "Number of established connections.", func(s sql.DBStats) string { return strconv.Itoa(s.OpenConnections) }},
+		{"pgx_pool_in_use_connections", "gauge", "Number of connections in use.", func(s sql.DBStats) string { return strconv.Itoa(s.InUse) }},
+		{"pgx_pool_idle_connections", "gauge", "Number of idle connections.", func(s sql.DBStats) string { return strconv.Itoa(s.Idle) }},
+		{"pgx_pool_wait_count", "counter", "Total number of connections waited for.", func(s sql.DBStats) string { return strconv.FormatInt(s.WaitCount, 10) }},
+		{"pgx_pool_wait_duration_seconds", "counter", "Total time blocked waiting for a new connection.", func(s sql.DBStats) string { return strconv.FormatFloat(s.WaitDuration.Seconds(), 'g', -1, 64) }},
+	}
+	for _, g := range poolMetrics {
+		writeHeader(builder, g.name, g.typ, g.help)
+		for _, table := range tables {
+			builder.WriteString(g.name + toLabels("table", table) + " " + g.value(snapshot.Pools[table]) + "\n")
+		}
+	}
+
+	n, e := io.WriteString(w, builder.String())
+	return int64(n), e
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, e := m.WriteTo(w)
+	if e != nil {
+		http.Error(w, e.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeHeader(builder *strings.Builder, name, typ, help string) {
+	builder.WriteString("# HELP " + name + " " + help + "\n")
+	builder.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// labelEscaper escapes label values as the Prometheus text format requires, other characters including non-ASCII ones are written as is
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// toLabels formats key-value pairs as prometheus labels
+func toLabels(kvs ...string) string {
+	labels := []string{}
+	for i := 0; i+1 < len(kvs); i += 2 {
+		labels = append(labels, kvs[i]+`="`+labelEscaper.Replace(kvs[i+1])+`"`)
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
@@ -0,0 +1,86 @@
+package pgx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestMetricsWriteTo(t *testing.T) {
+	m := NewMetrics(0.1, 0.01, 1)
+	for _, event := range []*QueryEvent{
+		{Table: "users", Query: "select * from users", Duration: 5 * time.Millisecond},
+		{Table: "users", Query: "select * from users", Duration: 50 * time.Millisecond},
+		{Table: "users", Query: "select * from users", Duration: 2 * time.Second},
+		{Table: "users", Query: "select * from users", Duration: time.Millisecond, Err: sql.ErrNoRows},
+		{Table: "users", Query: "insert into users", Duration: time.Millisecond, Err: &pq.Error{Code: "23505"}},
+		{Table: "users", Query: "insert into users", Duration: time.Millisecond, Err: errors.New("conn reset")},
+		{Table: "ü\"s\\\n", Query: "delete from x", Duration: time.Millisecond},
+	} {
+		m.AfterQuery(m.BeforeQuery(context.Background(), event), event)
+	}
+
+	builder := new(strings.Builder)
+	if _, e := m.WriteTo(builder); e != nil {
+		t.Fatal(e)
+	}
+	out := builder.String()
+	for _, line := range []string{
+		`# TYPE pgx_queries_total counter`,
+		`pgx_queries_total{table="users",operation="SELECT"} 4`,
+		`pgx_queries_total{table="users",operation="INSERT"} 2`,
+		`# TYPE pgx_query_duration_seconds histogram`,
+		`pgx_query_duration_seconds_bucket{table="users",operation="SELECT",le="0.01"} 2`,
+		`pgx_query_duration_seconds_bucket{table="users",operation="SELECT",le="0.1"} 3`,
+		`pgx_query_duration_seconds_bucket{table="users",operation="SELECT",le="1"} 3`,
+		`pgx_query_duration_seconds_bucket{table="users",operation="SELECT",le="+Inf"} 4`,
+		`pgx_query_duration_seconds_count{table="users",operation="SELECT"} 4`,
+		`pgx_query_errors_total{table="users",operation="INSERT",sqlstate="23505"} 1`,
+		`pgx_query_errors_total{table="users",operation="INSERT",sqlstate="unknown"} 1`,
+		`pgx_queries_total{table="ü\"s\\\n",operation="DELETE"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("WriteTo() output has no line %s, got:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, `operation="SELECT",sqlstate=`) {
+		t.Errorf("WriteTo() counts sql.ErrNoRows as error:\n%s", out)
+	}
+}
+
+func TestMetricsPoolTypes(t *testing.T) {
+	m := NewMetrics()
+	m.AddPool("users", &sql.DB{})
+	builder := new(strings.Builder)
+	if _, e := m.WriteTo(builder); e != nil {
+		t.Fatal(e)
+	}
+	out := builder.String()
+	for _, line := range []string{
+		"# TYPE pgx_pool_open_connections gauge",
+		"# TYPE pgx_pool_wait_count counter",
+		"# TYPE pgx_pool_wait_duration_seconds counter",
+		`pgx_pool_wait_count{table="users"} 0`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("WriteTo() output has no line %s, got:\n%s", line, out)
+		}
+	}
+}
+
+func TestNewMetricsCopiesBuckets(t *testing.T) {
+	buckets := []float64{1, 2}
+	m := NewMetrics(buckets...)
+	buckets[0] = 5
+	event := &QueryEvent{Table: "users", Query: "select 1", Duration: time.Second}
+	m.AfterQuery(context.Background(), event)
+	snapshot := m.Snapshot()
+	if snapshot.Buckets[0] != 1 || snapshot.Queries[0].Buckets[0] != 1 {
+		t.Errorf("Snapshot() = %+v, want buckets unaffected by caller", snapshot)
+	}
+}
@@ -0,0 +1,26 @@
+package pgx
+
+// Option configures a BaseModel on creation
+type Option func(b *BaseModel)
+
+// WithLogger sets the logger of the model, use Discard to silence it
+func WithLogger(logger Logger) Option {
+	return func(b *BaseModel) {
+		b.Logger = logger
+	}
+}
+
+// WithQueryHook adds hooks called around every statement the model executes
+func WithQueryHook(hooks ...QueryHook) Option {
+	return func(b *BaseModel) {
+		b.QueryHooks = append(b.QueryHooks, hooks...)
+	}
+}
+
+// WithMetrics collects query metrics and pool stats of the model into m
+func WithMetrics(m *Metrics) Option {
+	return func(b *BaseModel) {
+		m.AddPool(b.TableName, b.Pool)
+		b.QueryHooks = append(b.QueryHooks, m)
+	}
+}
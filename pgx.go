@@ -1,6 +1,7 @@
 package pgx
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/StevenZack/tools/strToolkit"
@@ -23,3 +24,29 @@ func toWhere(where string) string {
 	}
 	return where
 }
+
+// splitTopLevel splits s by commas outside parentheses and quotes
+func splitTopLevel(s string) ([]string, error) {
+	items := []string{}
+	depth := 0
+	quoted := false
+	start := 0
+	for i, c := range s {
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	if depth != 0 || quoted {
+		return nil, errors.New("unbalanced parentheses or quotes")
+	}
+	return append(items, s[start:]), nil
+}
@@ -0,0 +1,57 @@
+package pgx
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// pluck runs query and scans its single column into a slice of the c-th column's field type
+func (b *BaseModel) pluck(c int, query string, args []interface{}) (interface{}, error) {
+	t := b.Type.Field(b.fieldIndexes[c]).Type
+	rows, e := b.query(query, args...)
+	if e != nil {
+		return nil, toError(e, query)
+	}
+
+	vs := reflect.MakeSlice(reflect.SliceOf(t), 0, 2)
+	for rows.Next() {
+		v := reflect.New(t)
+		e = rows.Scan(v.Interface())
+		if e != nil {
+			break
+		}
+		vs = reflect.Append(vs, v.Elem())
+	}
+
+	// check err
+	if closeErr := rows.Close(); closeErr != nil {
+		return nil, fmt.Errorf("rows.Close() err:%w", closeErr)
+	}
+	if e != nil {
+		return nil, e
+	}
+	if e = rows.Err(); e != nil {
+		return nil, e
+	}
+	return vs.Interface(), nil
+}
+
+// PluckWhere returns values of column in rows matching 'where', as a slice of the column's field type (e.g. []int64 for ids)
+func (b *BaseModel) PluckWhere(column, where string, args ...interface{}) (interface{}, error) {
+	c, e := b.aggregateColumn(column)
+	if e != nil {
+		return nil, e
+	}
+	query := `select ` + column + ` from ` + b.fromTable() + toWhere(where)
+	return b.pluck(c, query, args)
+}
+
+// DistinctWhere returns unique values of column in rows matching 'where' in ascending order, as a slice of the column's field type
+func (b *BaseModel) DistinctWhere(column, where string, args ...interface{}) (interface{}, error) {
+	c, e := b.aggregateColumn(column)
+	if e != nil {
+		return nil, e
+	}
+	query := `select distinct ` + column + ` from ` + b.fromTable() + toWhere(where) + ` order by ` + column
+	return b.pluck(c, query, args)
+}
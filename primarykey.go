@@ -0,0 +1,91 @@
+package pgx
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// columnField returns the struct field of value mapped to the c-th column
+func (b *BaseModel) columnField(value reflect.Value, c int) reflect.Value {
+	return value.Field(b.fieldIndexes[c])
+}
+
+func (b *BaseModel) isPk(i int) bool {
+	for _, pk := range b.pks {
+		if pk == i {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPkTags returns db tags of primary key columns
+func (b *BaseModel) GetPkTags() []string {
+	tags := []string{}
+	for _, pk := range b.pks {
+		tags = append(tags, b.dbTags[pk])
+	}
+	return tags
+}
+
+// getPkWhere returns where condition on primary key columns, with placeholders starting from $offset+1
+func (b *BaseModel) getPkWhere(offset int) string {
+	builder := new(strings.Builder)
+	builder.WriteString(" where ")
+	for i, pk := range b.pks {
+		if i > 0 {
+			builder.WriteString(" and ")
+		}
+		builder.WriteString(b.dbTags[pk] + "=$" + strconv.Itoa(offset+i+1))
+	}
+	return builder.String()
+}
+
+// toPkArgs converts ids (primary key values, or a key struct) to args matching getPkWhere
+func (b *BaseModel) toPkArgs(ids []interface{}) ([]interface{}, error) {
+	if len(ids) == 1 {
+		value := reflect.ValueOf(ids[0])
+		if value.Kind() == reflect.Ptr {
+			value = value.Elem()
+		}
+		_, isValuer := ids[0].(driver.Valuer)
+		if value.Kind() == reflect.Struct && !isValuer && value.Type() != reflect.TypeOf(time.Time{}) {
+			return b.toPkArgsFromStruct(value)
+		}
+	}
+
+	if len(ids) != len(b.pks) {
+		return nil, errors.New("Expected " + strconv.Itoa(len(b.pks)) + " primary key values for table " + b.TableName + ", got " + strconv.Itoa(len(ids)))
+	}
+	return ids, nil
+}
+
+// toPkArgsFromStruct extracts primary key values from a model struct, or any struct with matching `db` tags
+func (b *BaseModel) toPkArgsFromStruct(value reflect.Value) ([]interface{}, error) {
+	args := []interface{}{}
+	if value.Type() == b.Type {
+		for _, pk := range b.pks {
+			args = append(args, b.columnField(value, pk).Interface())
+		}
+		return args, nil
+	}
+
+	fields := make(map[string]int)
+	for i := 0; i < value.NumField(); i++ {
+		if dbTag, ok := value.Type().Field(i).Tag.Lookup("db"); ok {
+			fields[dbTag] = i
+		}
+	}
+	for _, pk := range b.pks {
+		i, ok := fields[b.dbTags[pk]]
+		if !ok {
+			return nil, errors.New("Key struct " + value.Type().String() + " has no field for primary key column " + b.dbTags[pk])
+		}
+		args = append(args, value.Field(i).Interface())
+	}
+	return args, nil
+}
@@ -0,0 +1,66 @@
+package pgx
+
+import "errors"
+
+// Select returns a copy of b whose Find, FindWhere and QueryWhere only select the given columns, leaving other fields at zero values.
+// Primary keys are always selected
+func (b *BaseModel) Select(columns ...string) *BaseModel {
+	c := *b
+	c.selects = append(append([]string{}, b.selects...), columns...)
+	return &c
+}
+
+// Omit returns a copy of b whose Find, FindWhere and QueryWhere select all columns except the given ones, leaving their fields at zero values.
+// Primary keys are always selected
+func (b *BaseModel) Omit(columns ...string) *BaseModel {
+	c := *b
+	c.omits = append(append([]string{}, b.omits...), columns...)
+	return &c
+}
+
+// checkProjection validates columns passed to Select and Omit
+func (b *BaseModel) checkProjection() error {
+	for _, columns := range [][]string{b.selects, b.omits} {
+		for _, column := range columns {
+			if b.columnIndex(column) == -1 {
+				return errors.New("table " + b.TableName + " has no column " + column)
+			}
+		}
+	}
+	return nil
+}
+
+// isSelected reports whether the i-th column is selected by Select and Omit
+func (b *BaseModel) isSelected(i int) bool {
+	if b.isPk(i) {
+		return true
+	}
+	for _, column := range b.omits {
+		if column == b.dbTags[i] {
+			return false
+		}
+	}
+	if len(b.selects) == 0 {
+		return true
+	}
+	for _, column := range b.selects {
+		if column == b.dbTags[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// selectColumns returns fieldIndexes and names of columns narrowed by Select and Omit
+func (b *BaseModel) selectColumns() ([]int, []string) {
+	fieldIndexes := []int{}
+	columns := []string{}
+	for i, dbTag := range b.dbTags {
+		if !b.isSelected(i) {
+			continue
+		}
+		columns = append(columns, dbTag)
+		fieldIndexes = append(fieldIndexes, b.fieldIndexes[i])
+	}
+	return fieldIndexes, columns
+}
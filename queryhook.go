@@ -0,0 +1,110 @@
+package pgx
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// QueryEvent describes a statement executed by BaseModel, Rows is -1 if unknown
+type QueryEvent struct {
+	Table    string
+	Query    string
+	Args     []interface{}
+	Start    time.Time
+	Duration time.Duration
+	Rows     int64
+	Err      error
+}
+
+// QueryHook is called before and after every statement BaseModel executes, including schema sync DDL
+type QueryHook interface {
+	BeforeQuery(ctx context.Context, event *QueryEvent) context.Context
+	AfterQuery(ctx context.Context, event *QueryEvent)
+}
+
+// Operation returns the upper-cased first keyword of the query, e.g. SELECT, INSERT, ALTER
+func (q *QueryEvent) Operation() string {
+	query := strings.TrimSpace(q.Query)
+	if i := strings.IndexAny(query, " \n\t"); i > -1 {
+		query = query[:i]
+	}
+	return strings.ToUpper(query)
+}
+
+// Span is the subset of an OpenTelemetry span used by TracingHook
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(e error)
+	End()
+}
+
+// SpanTracer starts spans, adapt an OpenTelemetry tracer to it to export query spans
+type SpanTracer interface {
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// TracingHook emits a span for every statement, named like "SELECT users", with OpenTelemetry database semantic attributes
+type TracingHook struct {
+	Tracer SpanTracer
+}
+
+type spanKey struct{}
+
+func NewTracingHook(tracer SpanTracer) *TracingHook {
+	return &TracingHook{
+		Tracer: tracer,
+	}
+}
+
+func (t *TracingHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	ctx, span := t.Tracer.StartSpan(ctx, event.Operation()+" "+event.Table)
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func (t *TracingHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return
+	}
+	span.SetAttribute("db.system", "postgresql")
+	span.SetAttribute("db.sql.table", event.Table)
+	span.SetAttribute("db.operation", event.Operation())
+	span.SetAttribute("db.statement", event.Query)
+	if event.Rows > -1 {
+		span.SetAttribute("db.rows_affected", event.Rows)
+	}
+	if event.Err != nil {
+		span.RecordError(event.Err)
+	}
+	span.End()
+}
+
+// SlowQueryHook logs statements taking Threshold or longer at warn level
+type SlowQueryHook struct {
+	Threshold time.Duration
+	Logger    Logger
+}
+
+func NewSlowQueryHook(threshold time.Duration, logger Logger) *SlowQueryHook {
+	return &SlowQueryHook{
+		Threshold: threshold,
+		Logger:    logger,
+	}
+}
+
+func (s *SlowQueryHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	return ctx
+}
+
+func (s *SlowQueryHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	if event.Duration < s.Threshold {
+		return
+	}
+	s.Logger.Log(LevelWarn, "slow query",
+		Field{Key: "table", Value: event.Table},
+		Field{Key: "query", Value: event.Query},
+		Field{Key: "duration", Value: event.Duration},
+		Field{Key: "rows", Value: event.Rows},
+	)
+}
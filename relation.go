@@ -0,0 +1,297 @@
+package pgx
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/StevenZack/tools/strToolkit"
+	"github.com/iancoleman/strcase"
+	"github.com/lib/pq"
+)
+
+type relation struct {
+	field int
+	name  string
+	kind  string
+	// fk is the referencing column, on the related table for has_one/has_many, on this table for belongs_to
+	fk string
+	// ref is the referenced column, on this table for has_one/has_many, on the related table for belongs_to. Primary key by default
+	ref  string
+	elem reflect.Type
+	// through is the join table of many2many relation, with column fk referencing this table and assocFk referencing the related table
+	through string
+	assocFk string
+}
+
+// modelKey identifies a created model by its database, schema and struct type, so models of the same struct on different databases don't collide
+type modelKey struct {
+	dsn    string
+	schema string
+	t      reflect.Type
+}
+
+// models registers created models by modelKey, so that relations can find the model of related structs on the same database
+var models sync.Map
+
+func registerModel(b *BaseModel) {
+	models.Store(modelKey{dsn: b.Dsn, schema: b.Schema, t: b.Type}, b)
+}
+
+// modelOf returns the model of struct type t created on the same database and schema as b, carrying b's context
+func (b *BaseModel) modelOf(t reflect.Type) (*BaseModel, error) {
+	v, ok := models.Load(modelKey{dsn: b.Dsn, schema: b.Schema, t: t})
+	if !ok {
+		return nil, errors.New("model of " + t.String() + " is not created on the same database and schema as " + b.TableName + ", call NewBaseModel on it first")
+	}
+	if b.ctx != nil {
+		return v.(*BaseModel).WithContext(b.ctx), nil
+	}
+	return v.(*BaseModel), nil
+}
+
+// toRelation parses rel tag with format like: "has_many,fk=user_id", "has_one,fk=user_id,ref=id", "belongs_to,fk=user_id", "many2many,through=user_roles,fk=user_id,assoc_fk=role_id"
+func toRelation(owner reflect.Type, index int, field reflect.StructField, tag string) (relation, error) {
+	rel := relation{
+		field: index,
+		name:  field.Name,
+		kind:  strToolkit.SubBefore(tag, ",", tag),
+	}
+	vs, e := url.ParseQuery(strings.ReplaceAll(strToolkit.SubAfter(tag, ",", ""), ",", "&"))
+	if e != nil {
+		return rel, errors.New("field '" + field.Name + "', invalid rel tag format:" + tag)
+	}
+	for k := range vs {
+		switch k {
+		case "fk":
+			rel.fk = vs.Get(k)
+		case "ref":
+			rel.ref = vs.Get(k)
+		case "through":
+			rel.through = vs.Get(k)
+		case "assoc_fk":
+			rel.assocFk = vs.Get(k)
+		default:
+			return rel, errors.New("field '" + field.Name + "', unsupported rel key:" + k)
+		}
+	}
+
+	t := field.Type
+	switch rel.kind {
+	case "has_many":
+		if t.Kind() != reflect.Slice {
+			return rel, errors.New("field '" + field.Name + "' with has_many relation must be slice type")
+		}
+		t = t.Elem()
+		if rel.fk == "" {
+			rel.fk = strcase.ToSnake(owner.Name()) + "_id"
+		}
+	case "many2many":
+		if t.Kind() != reflect.Slice {
+			return rel, errors.New("field '" + field.Name + "' with many2many relation must be slice type")
+		}
+		t = t.Elem()
+		if rel.fk == "" {
+			rel.fk = strcase.ToSnake(owner.Name()) + "_id"
+		}
+	case "has_one":
+		if rel.fk == "" {
+			rel.fk = strcase.ToSnake(owner.Name()) + "_id"
+		}
+	case "belongs_to":
+		if rel.fk == "" {
+			rel.fk = strcase.ToSnake(field.Name) + "_id"
+		}
+	default:
+		return rel, errors.New("field '" + field.Name + "', unsupported relation:" + rel.kind)
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return rel, errors.New("field '" + field.Name + "', relation must be struct type:" + field.Type.String())
+	}
+	rel.elem = t
+	if rel.kind == "many2many" {
+		if rel.assocFk == "" {
+			rel.assocFk = strcase.ToSnake(t.Name()) + "_id"
+		}
+		if rel.through == "" {
+			rel.through = ToTableName(owner.Name()) + "_" + ToTableName(t.Name())
+		}
+	}
+	return rel, nil
+}
+
+// columnIndex returns the index of column dbTag, or -1 if not found
+func (b *BaseModel) columnIndex(dbTag string) int {
+	for i, v := range b.dbTags {
+		if v == dbTag {
+			return i
+		}
+	}
+	return -1
+}
+
+// refColumn returns the index of column ref, defaults to the single primary key
+func (b *BaseModel) refColumn(ref string) (int, error) {
+	if ref == "" {
+		if len(b.pks) != 1 {
+			return -1, errors.New("table " + b.TableName + " has composite primary keys, relation ref must be set")
+		}
+		return b.pks[0], nil
+	}
+	c := b.columnIndex(ref)
+	if c == -1 {
+		return -1, errors.New("table " + b.TableName + " has no column " + ref)
+	}
+	return c, nil
+}
+
+// Preload returns a copy of b whose Find, FindWhere and QueryWhere also load the named relation fields, batching each relation in one query
+func (b *BaseModel) Preload(names ...string) *BaseModel {
+	c := *b
+	c.preloads = append(append([]string{}, b.preloads...), names...)
+	return &c
+}
+
+func (b *BaseModel) relationOf(name string) (relation, error) {
+	for _, rel := range b.relations {
+		if rel.name == name {
+			return rel, nil
+		}
+	}
+	return relation{}, errors.New("type " + b.Type.String() + " has no relation field " + name)
+}
+
+// preload loads relations named by Preload into vs ([]*struct)
+func (b *BaseModel) preload(vs reflect.Value) error {
+	if vs.Len() == 0 {
+		return nil
+	}
+	for _, name := range b.preloads {
+		rel, e := b.relationOf(name)
+		if e != nil {
+			return e
+		}
+		related, e := b.modelOf(rel.elem)
+		if e != nil {
+			return e
+		}
+		if rel.kind == "many2many" {
+			e = b.preloadMany2many(vs, rel, related)
+			if e != nil {
+				return e
+			}
+			continue
+		}
+
+		// key column on parent, and matching column on related table
+		var keyC, relatedC int
+		if rel.kind == "belongs_to" {
+			keyC = b.columnIndex(rel.fk)
+			if keyC == -1 {
+				return errors.New("table " + b.TableName + " has no column " + rel.fk)
+			}
+			relatedC, e = related.refColumn(rel.ref)
+		} else {
+			keyC, e = b.refColumn(rel.ref)
+			if e == nil {
+				relatedC = related.columnIndex(rel.fk)
+				if relatedC == -1 {
+					e = errors.New("table " + related.TableName + " has no column " + rel.fk)
+				}
+			}
+		}
+		if e != nil {
+			return e
+		}
+
+		//keys
+		keys := []interface{}{}
+		seen := make(map[string]bool)
+		for i := 0; i < vs.Len(); i++ {
+			key, ok := toRelationKey(b.columnField(vs.Index(i).Elem(), keyC))
+			if !ok || seen[key] {
+				continue
+			}
+			seen[key] = true
+			keys = append(keys, b.columnField(vs.Index(i).Elem(), keyC).Interface())
+		}
+
+		//load
+		groups := make(map[string][]reflect.Value)
+		if len(keys) > 0 {
+			children, e := related.QueryWhere(related.dbTags[relatedC]+" = any($1)", pq.Array(keys))
+			if e != nil {
+				return e
+			}
+			childValues := reflect.ValueOf(children)
+			for i := 0; i < childValues.Len(); i++ {
+				child := childValues.Index(i)
+				key, ok := toRelationKey(related.columnField(child.Elem(), relatedC))
+				if !ok {
+					continue
+				}
+				groups[key] = append(groups[key], child)
+			}
+		}
+
+		//assign
+		for i := 0; i < vs.Len(); i++ {
+			parent := vs.Index(i).Elem()
+			var group []reflect.Value
+			if key, ok := toRelationKey(b.columnField(parent, keyC)); ok {
+				group = groups[key]
+			}
+			assignRelation(parent.Field(rel.field), group)
+		}
+	}
+	return nil
+}
+
+// toRelationKey converts key to a comparable map key through its driver value, so sql.NullInt64{5, true} matches 5. NULL keys are not ok
+func toRelationKey(key reflect.Value) (string, bool) {
+	v, e := driver.DefaultParameterConverter.ConvertValue(key.Interface())
+	if e != nil {
+		return fmt.Sprint(key.Interface()), true
+	}
+	if v == nil {
+		return "", false
+	}
+	if bs, ok := v.([]byte); ok {
+		return string(bs), true
+	}
+	return fmt.Sprint(v), true
+}
+
+// assignRelation sets field (slice, *struct or struct) with loaded children ([]*struct values)
+func assignRelation(field reflect.Value, children []reflect.Value) {
+	t := field.Type()
+	if t.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(t, 0, len(children))
+		for _, child := range children {
+			if t.Elem().Kind() == reflect.Ptr {
+				slice = reflect.Append(slice, child)
+			} else {
+				slice = reflect.Append(slice, child.Elem())
+			}
+		}
+		field.Set(slice)
+		return
+	}
+
+	if len(children) == 0 {
+		field.Set(reflect.Zero(t))
+		return
+	}
+	if t.Kind() == reflect.Ptr {
+		field.Set(children[0])
+		return
+	}
+	field.Set(children[0].Elem())
+}
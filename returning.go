@@ -0,0 +1,55 @@
+package pgx
+
+import "strings"
+
+// GetReturningSQL returns fieldIndexes, and returning clause of columns narrowed by Select and Omit
+func (b *BaseModel) GetReturningSQL() ([]int, string) {
+	fieldIndexes, columns := b.selectColumns()
+	return fieldIndexes, ` returning ` + strings.Join(columns, ",")
+}
+
+// queryReturning runs query with returning clause, scanning affected rows into []*T
+func (b *BaseModel) queryReturning(query string, args []interface{}) (interface{}, error) {
+	e := b.checkProjection()
+	if e != nil {
+		return nil, e
+	}
+	fieldIndexes, returning := b.GetReturningSQL()
+	query = query + returning
+	rows, e := b.query(query, args...)
+	if e != nil {
+		return nil, toError(e, query)
+	}
+	return b.scanModels(rows, fieldIndexes)
+}
+
+// UpdateSetReturning is UpdateSet returning the updated rows as []*T
+func (b *BaseModel) UpdateSetReturning(sets string, where string, args ...interface{}) (interface{}, error) {
+	query := `update ` + b.TableName + ` set ` + b.toAutoTimeSets(sets) + b.toUpdateWhere(where)
+	return b.queryReturning(query, args)
+}
+
+// DeleteWhereReturning is DeleteWhere returning the deleted rows as []*T. Tables supporting soft delete get the rows marked as deleted instead
+func (b *BaseModel) DeleteWhereReturning(where string, args ...interface{}) (interface{}, error) {
+	if b.softDelete == "" {
+		return b.HardDeleteWhereReturning(where, args...)
+	}
+	e := b.beforeDeleteWhere(where, args)
+	if e != nil {
+		return nil, e
+	}
+
+	query := `update ` + b.TableName + ` set ` + b.softDelete + `=now()` + b.toSoftWhere(where)
+	return b.queryReturning(query, args)
+}
+
+// HardDeleteWhereReturning is HardDeleteWhere returning the deleted rows as []*T
+func (b *BaseModel) HardDeleteWhereReturning(where string, args ...interface{}) (interface{}, error) {
+	e := b.WithDeleted().beforeDeleteWhere(where, args)
+	if e != nil {
+		return nil, e
+	}
+
+	query := `delete from ` + b.TableName + toWhere(where)
+	return b.queryReturning(query, args)
+}
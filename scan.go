@@ -0,0 +1,132 @@
+package pgx
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// toFieldPaths maps `db` tags of struct type t to field index paths, descending into embedded structs
+func toFieldPaths(t reflect.Type) map[string][]int {
+	paths := make(map[string][]int)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if dbTag, ok := field.Tag.Lookup("db"); ok {
+			if dbTag != "-" {
+				paths[dbTag] = []int{i}
+			}
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for dbTag, path := range toFieldPaths(field.Type) {
+				// outer fields take precedence
+				if _, ok := paths[dbTag]; !ok {
+					paths[dbTag] = append([]int{i}, path...)
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// scanRows scans rows into a new struct of type t for each row, calling fn with its pointer until fn returns false. Columns without matching `db` tag are ignored
+func scanRows(rows *sql.Rows, t reflect.Type, fn func(v reflect.Value) bool) error {
+	columns, e := rows.Columns()
+	if e != nil {
+		rows.Close()
+		return e
+	}
+	paths := toFieldPaths(t)
+
+	for rows.Next() {
+		v := reflect.New(t)
+		dest := []interface{}{}
+		for _, column := range columns {
+			path, ok := paths[column]
+			if !ok {
+				dest = append(dest, new(interface{}))
+				continue
+			}
+			dest = append(dest, v.Elem().FieldByIndex(path).Addr().Interface())
+		}
+		e = rows.Scan(dest...)
+		if e != nil {
+			break
+		}
+		if !fn(v) {
+			break
+		}
+	}
+
+	//check err
+	if closeErr := rows.Close(); closeErr != nil {
+		return fmt.Errorf("rows.Close() err:%w", closeErr)
+	}
+	if e != nil {
+		return e
+	}
+	return rows.Err()
+}
+
+// Select runs query on db and scans all rows into dst (*[]struct or *[]*struct), matching column names to `db` tags
+func Select(db *sql.DB, dst interface{}, query string, args ...interface{}) error {
+	dstValue := reflect.ValueOf(dst)
+	if dstValue.Kind() != reflect.Ptr || dstValue.Elem().Kind() != reflect.Slice {
+		return errors.New("dst must be pointer to slice:" + dstValue.Type().String())
+	}
+	sliceValue := dstValue.Elem()
+	t := sliceValue.Type().Elem()
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return errors.New("dst element must be struct type:" + t.String())
+	}
+
+	rows, e := db.Query(query, args...)
+	if e != nil {
+		return toError(e, query)
+	}
+	vs := reflect.MakeSlice(sliceValue.Type(), 0, 2)
+	e = scanRows(rows, t, func(v reflect.Value) bool {
+		if isPtr {
+			vs = reflect.Append(vs, v)
+		} else {
+			vs = reflect.Append(vs, v.Elem())
+		}
+		return true
+	})
+	if e != nil {
+		return e
+	}
+	sliceValue.Set(vs)
+	return nil
+}
+
+// Get runs query on db and scans the first row into dst (*struct), returns ErrNotFound if there's no row
+func Get(db *sql.DB, dst interface{}, query string, args ...interface{}) error {
+	dstValue := reflect.ValueOf(dst)
+	if dstValue.Kind() != reflect.Ptr || dstValue.Elem().Kind() != reflect.Struct {
+		return errors.New("dst must be pointer to struct:" + dstValue.Type().String())
+	}
+
+	rows, e := db.Query(query, args...)
+	if e != nil {
+		return toError(e, query)
+	}
+	found := false
+	e = scanRows(rows, dstValue.Elem().Type(), func(v reflect.Value) bool {
+		dstValue.Elem().Set(v.Elem())
+		found = true
+		return false
+	})
+	if e != nil {
+		return e
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
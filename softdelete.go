@@ -0,0 +1,97 @@
+package pgx
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/StevenZack/tools/strToolkit"
+)
+
+// fromTable returns the table to select from, excluding soft deleted rows unless WithDeleted is used
+func (b *BaseModel) fromTable() string {
+	if b.softDelete == "" || b.withDeleted {
+		return b.TableName
+	}
+	return `(select * from ` + b.TableName + ` where ` + b.softDelete + ` is null) as ` + b.TableName
+}
+
+// toSoftWhere converts where to a condition that only matches rows not soft deleted yet
+func (b *BaseModel) toSoftWhere(where string) string {
+	where = strings.TrimPrefix(strToolkit.TrimStart(where, " "), "where")
+	if strings.TrimSpace(where) == "" {
+		return ` where ` + b.softDelete + ` is null`
+	}
+	return ` where (` + where + `) and ` + b.softDelete + ` is null`
+}
+
+// toUpdateWhere converts where to a condition for updates, skipping soft deleted rows unless WithDeleted is used
+func (b *BaseModel) toUpdateWhere(where string) string {
+	if b.softDelete == "" || b.withDeleted {
+		return toWhere(where)
+	}
+	return b.toSoftWhere(where)
+}
+
+// WithDeleted returns a copy of b whose queries include soft deleted rows
+func (b *BaseModel) WithDeleted() *BaseModel {
+	c := *b
+	c.withDeleted = true
+	return &c
+}
+
+// Restore clears the soft delete mark of a document by primary key values, or by a key struct
+func (b *BaseModel) Restore(ids ...interface{}) (int64, error) {
+	if b.softDelete == "" {
+		return 0, fmt.Errorf("table %s doesn't support soft delete", b.TableName)
+	}
+	args, e := b.toPkArgs(ids)
+	if e != nil {
+		return 0, e
+	}
+
+	query := `update ` + b.TableName + ` set ` + b.softDelete + `=null` + b.getPkWhere(0)
+	result, e := b.exec(query, args...)
+	if e != nil {
+		return 0, toError(e, query)
+	}
+	return result.RowsAffected()
+}
+
+// HardDelete deletes a document by primary key values, or by a key struct, even if the table supports soft delete
+func (b *BaseModel) HardDelete(ids ...interface{}) (int64, error) {
+	args, e := b.toPkArgs(ids)
+	if e != nil {
+		return 0, e
+	}
+	e = b.WithDeleted().beforeDelete(ids)
+	if e != nil {
+		if e == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, e
+	}
+
+	query := `delete from ` + b.TableName + b.getPkWhere(0)
+	result, e := b.exec(query, args...)
+	if e != nil {
+		return 0, toError(e, query)
+	}
+	return result.RowsAffected()
+}
+
+// HardDeleteWhere deletes documents that match 'where' condition, even if the table supports soft delete
+func (b *BaseModel) HardDeleteWhere(where string, args ...interface{}) (int64, error) {
+	e := b.WithDeleted().beforeDeleteWhere(where, args)
+	if e != nil {
+		return 0, e
+	}
+	where = toWhere(where)
+
+	query := `delete from ` + b.TableName + where
+	result, e := b.exec(query, args...)
+	if e != nil {
+		return 0, toError(e, query)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,185 @@
+package pgx
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type (
+	fieldValidator struct {
+		index    int
+		field    string
+		column   string
+		limit    int
+		required bool
+		min      *float64
+		max      *float64
+		regex    *regexp.Regexp
+		enum     []string
+	}
+	// ValidationError is a single tag rule violated by a field
+	ValidationError struct {
+		Field   string
+		Column  string
+		Rule    string
+		Message string
+	}
+	// ValidationErrors lists every violation found in a struct before writing it
+	ValidationErrors []ValidationError
+)
+
+func (v ValidationError) Error() string {
+	return "field " + v.Field + ": " + v.Message
+}
+
+func (vs ValidationErrors) Error() string {
+	msgs := []string{}
+	for _, v := range vs {
+		msgs = append(msgs, v.Error())
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// toFieldValidator parses validation tags (required, min, max, regex, enum) of field, returns nil if no rule is declared
+func toFieldValidator(index int, field reflect.StructField, column string, limit int) (*fieldValidator, error) {
+	v := &fieldValidator{
+		index:  index,
+		field:  field.Name,
+		column: column,
+		limit:  limit,
+	}
+	_, v.required = field.Tag.Lookup("required")
+	for _, key := range []string{"min", "max"} {
+		s, ok := field.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		f, e := strconv.ParseFloat(s, 64)
+		if e != nil {
+			return nil, errors.New("Invalid " + key + " tag format:" + s + " for field " + field.Name)
+		}
+		if key == "min" {
+			v.min = &f
+		} else {
+			v.max = &f
+		}
+	}
+	if s, ok := field.Tag.Lookup("regex"); ok {
+		regex, e := regexp.Compile(s)
+		if e != nil {
+			return nil, errors.New("Invalid regex tag format:" + s + " for field " + field.Name)
+		}
+		v.regex = regex
+	}
+	if s, ok := field.Tag.Lookup("enum"); ok {
+		v.enum = strings.Split(s, ",")
+	}
+
+	if v.limit == 0 && !v.required && v.min == nil && v.max == nil && v.regex == nil && v.enum == nil {
+		return nil, nil
+	}
+	return v, nil
+}
+
+func (v *fieldValidator) validate(value reflect.Value) ValidationErrors {
+	errs := ValidationErrors{}
+	fail := func(rule, msg string) {
+		errs = append(errs, ValidationError{Field: v.field, Column: v.column, Rule: rule, Message: msg})
+	}
+
+	if value.IsZero() && v.required {
+		fail("required", "is required")
+		return errs
+	}
+
+	//null values only violate required
+	valid := true
+	switch x := value.Interface().(type) {
+	case sql.NullString:
+		value, valid = reflect.ValueOf(x.String), x.Valid
+	case sql.NullInt32:
+		value, valid = reflect.ValueOf(x.Int32), x.Valid
+	case sql.NullInt64:
+		value, valid = reflect.ValueOf(x.Int64), x.Valid
+	case sql.NullFloat64:
+		value, valid = reflect.ValueOf(x.Float64), x.Valid
+	}
+	if !valid {
+		return errs
+	}
+
+	var num float64
+	isNum := true
+	switch value.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		num = float64(value.Int())
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		num = float64(value.Uint())
+	case reflect.Float64, reflect.Float32:
+		num = value.Float()
+	case reflect.String:
+		num = float64(utf8.RuneCountInString(value.String()))
+	default:
+		isNum = false
+	}
+
+	//regex and enum match the text form of non-string values, like "9" for an int field
+	s := fmt.Sprint(value.Interface())
+	if value.Kind() == reflect.String {
+		s = value.String()
+		if v.limit > 0 && utf8.RuneCountInString(s) > v.limit {
+			fail("limit", "length exceeds limit "+strconv.Itoa(v.limit))
+		}
+	}
+	if v.regex != nil && !v.regex.MatchString(s) {
+		fail("regex", "doesn't match "+v.regex.String())
+	}
+	if v.enum != nil {
+		found := false
+		for _, e := range v.enum {
+			if e == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("enum", "must be one of "+strings.Join(v.enum, ","))
+		}
+	}
+	if isNum && v.min != nil && num < *v.min {
+		fail("min", "must not be less than "+strconv.FormatFloat(*v.min, 'f', -1, 64))
+	}
+	if isNum && v.max != nil && num > *v.max {
+		fail("max", "must not be greater than "+strconv.FormatFloat(*v.max, 'f', -1, 64))
+	}
+	return errs
+}
+
+// Validate checks v (*struct or struct type) against validation tags, returns ValidationErrors listing every violation
+func (b *BaseModel) Validate(v interface{}) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Type() != b.Type {
+		return errors.New("Wrong validate type:" + value.Type().String() + " for table " + b.TableName)
+	}
+	return b.validate(value)
+}
+
+func (b *BaseModel) validate(value reflect.Value) error {
+	errs := ValidationErrors{}
+	for _, v := range b.validators {
+		errs = append(errs, v.validate(value.Field(v.index))...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
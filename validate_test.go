@@ -0,0 +1,103 @@
+package pgx
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+type validateModel struct {
+	ID     uint64         `db:"id"`
+	Name   string         `db:"name" required:"" limit:"5"`
+	Age    int            `db:"age" min:"1" max:"150"`
+	Level  int            `db:"level" enum:"1,2"`
+	Code   int            `db:"code" regex:"^[0-9]{3}$"`
+	Status string         `db:"status" enum:"on,off"`
+	Email  sql.NullString `db:"email" regex:"@"`
+	Plain  string         `db:"plain"`
+}
+
+func TestToFieldValidator(t *testing.T) {
+	typ := reflect.TypeOf(validateModel{})
+	tests := []struct {
+		field string
+		limit int
+		nil   bool
+		err   bool
+	}{
+		{field: "ID", nil: true},
+		{field: "Plain", nil: true},
+		{field: "Plain", limit: 3},
+		{field: "Name"},
+		{field: "Age"},
+		{field: "Level"},
+		{field: "Code"},
+	}
+	for _, test := range tests {
+		field, _ := typ.FieldByName(test.field)
+		v, e := toFieldValidator(field.Index[0], field, field.Tag.Get("db"), test.limit)
+		if (e != nil) != test.err {
+			t.Errorf("toFieldValidator(%s) error = %v, want error %v", test.field, e, test.err)
+		}
+		if (v == nil) != test.nil {
+			t.Errorf("toFieldValidator(%s) = %v, want nil %v", test.field, v, test.nil)
+		}
+	}
+
+	for _, tag := range []reflect.StructTag{`min:"a"`, `max:"1x"`, `regex:"("`} {
+		field := reflect.StructField{Name: "Bad", Type: reflect.TypeOf(0), Tag: tag}
+		if _, e := toFieldValidator(0, field, "bad", 0); e == nil {
+			t.Errorf("toFieldValidator with tag %s returns no error", tag)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	b := &BaseModel{Type: reflect.TypeOf(validateModel{})}
+	for i := 0; i < b.Type.NumField(); i++ {
+		field := b.Type.Field(i)
+		limit := 0
+		if field.Name == "Name" {
+			limit = 5
+		}
+		v, e := toFieldValidator(i, field, field.Tag.Get("db"), limit)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if v != nil {
+			b.validators = append(b.validators, v)
+		}
+	}
+
+	valid := validateModel{Name: "abc", Age: 20, Level: 1, Code: 123, Status: "on"}
+	tests := []struct {
+		name  string
+		edit  func(v *validateModel)
+		rules []string
+	}{
+		{name: "valid", edit: func(v *validateModel) {}},
+		{name: "required", edit: func(v *validateModel) { v.Name = "" }, rules: []string{"required"}},
+		{name: "limit", edit: func(v *validateModel) { v.Name = "abcdef" }, rules: []string{"limit"}},
+		{name: "min", edit: func(v *validateModel) { v.Age = 0 }, rules: []string{"min"}},
+		{name: "max", edit: func(v *validateModel) { v.Age = 151 }, rules: []string{"max"}},
+		{name: "int enum", edit: func(v *validateModel) { v.Level = 9 }, rules: []string{"enum"}},
+		{name: "int regex", edit: func(v *validateModel) { v.Code = 12 }, rules: []string{"regex"}},
+		{name: "string enum", edit: func(v *validateModel) { v.Status = "x" }, rules: []string{"enum"}},
+		{name: "null skipped", edit: func(v *validateModel) { v.Email = sql.NullString{String: "x"} }},
+		{name: "null regex", edit: func(v *validateModel) { v.Email = NullString("x") }, rules: []string{"regex"}},
+		{name: "every violation", edit: func(v *validateModel) { v.Age = 0; v.Level = 3 }, rules: []string{"min", "enum"}},
+	}
+	for _, test := range tests {
+		v := valid
+		test.edit(&v)
+		rules := []string{}
+		if e := b.Validate(&v); e != nil {
+			for _, ve := range e.(ValidationErrors) {
+				rules = append(rules, ve.Rule)
+			}
+		}
+		if len(rules) != len(test.rules) || (len(rules) > 0 && !reflect.DeepEqual(rules, test.rules)) {
+			t.Errorf("%s: Validate() violates %v, want %v", test.name, rules, test.rules)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package pgx
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrStaleObject is returned by Update when the row's version column no longer matches, meaning it was modified concurrently
+var ErrStaleObject = errors.New("stale object")
+
+// increaseVersion increases the version field of value after a successful update
+func (b *BaseModel) increaseVersion(value reflect.Value) {
+	field := b.columnField(value, b.version)
+	switch field.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16:
+		field.SetInt(field.Int() + 1)
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16:
+		field.SetUint(field.Uint() + 1)
+	}
+}